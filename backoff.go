@@ -0,0 +1,89 @@
+package timer
+
+import (
+	"sync"
+	"time"
+)
+
+// A BackoffTimer is a Timer with a capped exponential backoff, as used
+// for reconnects: each call to Next schedules it after twice the previous
+// delay, starting at the base delay and capped at the maximum delay.
+// A BackoffTimer must be created with NewBackoffTimer.
+type BackoffTimer struct {
+	C <-chan time.Time // The channel on which the fires are delivered.
+
+	t *Timer
+
+	mutex  sync.Mutex
+	base   time.Duration
+	max    time.Duration
+	jitter time.Duration
+	next   time.Duration
+}
+
+// NewBackoffTimer creates a new stopped BackoffTimer with the base delay
+// base and the maximum delay max. Call Next to schedule it.
+// The base delay must be greater than zero and not greater than max;
+// if not, NewBackoffTimer will panic.
+func NewBackoffTimer(base, max time.Duration) *BackoffTimer {
+	if base <= 0 || base > max {
+		panic("timer: invalid delays for NewBackoffTimer")
+	}
+	t := NewStoppedTimer()
+	return &BackoffTimer{
+		C:    t.C,
+		t:    t,
+		base: base,
+		max:  max,
+		next: base,
+	}
+}
+
+// SetJitter applies a random offset in [-jitter, +jitter] to the delays
+// of subsequent calls to Next, to avoid many clients retrying in sync.
+func (b *BackoffTimer) SetJitter(jitter time.Duration) {
+	b.mutex.Lock()
+	b.jitter = jitter
+	b.mutex.Unlock()
+}
+
+// Next changes the timer to expire after the next delay of the backoff
+// and doubles the delay for the following call, up to the maximum delay.
+// It behaves like Reset and clears the channel b.C. It returns true if
+// the timer had been active, false if the timer had expired or been stopped.
+func (b *BackoffTimer) Next() bool {
+	if b.t == nil {
+		panic("timer: Next called on uninitialized BackoffTimer")
+	}
+	b.mutex.Lock()
+	d := jitterDuration(b.next, b.jitter)
+	if b.next > b.max/2 {
+		b.next = b.max
+	} else {
+		b.next *= 2
+	}
+	b.mutex.Unlock()
+
+	return resetTimer(b.t, d, 0)
+}
+
+// ResetBackoff returns the delay of the next call to Next to the base delay,
+// typically after a successful attempt. The timer is not changed.
+func (b *BackoffTimer) ResetBackoff() {
+	if b.t == nil {
+		panic("timer: ResetBackoff called on uninitialized BackoffTimer")
+	}
+	b.mutex.Lock()
+	b.next = b.base
+	b.mutex.Unlock()
+}
+
+// Stop prevents the BackoffTimer from firing.
+// It returns true if the call stops the timer,
+// false if the timer has already expired or been stopped.
+func (b *BackoffTimer) Stop() bool {
+	if b.t == nil {
+		panic("timer: Stop called on uninitialized BackoffTimer")
+	}
+	return stopTimer(b.t)
+}
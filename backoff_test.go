@@ -0,0 +1,56 @@
+package timer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffTimer(t *testing.T) {
+	b := NewBackoffTimer(time.Second, 5*time.Second)
+	defer b.Stop()
+
+	for _, want := range []time.Duration{1, 2, 4, 5, 5} {
+		b.Next()
+		if _, d := b.t.Elapsed(); !approx(d, want*time.Second) {
+			t.Errorf("backoff: invalid delay %v, want %v", d, want*time.Second)
+		}
+	}
+
+	b.ResetBackoff()
+	if !b.Next() {
+		t.Errorf("backoff: was active is false")
+	}
+	if _, d := b.t.Elapsed(); !approx(d, time.Second) {
+		t.Errorf("backoff: invalid delay after reset %v", d)
+	}
+}
+
+// Report whether the scheduled duration d matches want. d is slightly
+// shorter, as time passes between computing the deadline and scheduling.
+func approx(d, want time.Duration) bool {
+	return d <= want && d > want-100*time.Millisecond
+}
+
+func TestBackoffTimerFire(t *testing.T) {
+	b := NewBackoffTimer(10*time.Millisecond, 20*time.Millisecond)
+	defer b.Stop()
+	b.SetJitter(time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		b.Next()
+		select {
+		case <-b.C:
+		case <-time.After(time.Second):
+			t.Fatalf("backoff: timer did not fire")
+		}
+	}
+}
+
+func TestBackoffTimerPanic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("backoff: no panic for invalid delays")
+		}
+	}()
+	NewBackoffTimer(time.Second, time.Millisecond)
+}
@@ -0,0 +1,79 @@
+package timer
+
+import (
+	"time"
+)
+
+// A Broadcaster wakes up any number of waiters when it fires,
+// unlike a Timer whose fire is received by a single receiver.
+// A Broadcaster must be created with NewBroadcaster.
+type Broadcaster struct {
+	t *Timer
+
+	// done is closed on fire. It is accessed in a locked context.
+	done chan struct{}
+}
+
+// NewBroadcaster creates a new Broadcaster that fires after at least
+// duration d.
+func NewBroadcaster(d time.Duration) *Broadcaster {
+	b := &Broadcaster{
+		done: make(chan struct{}),
+	}
+	b.t = &Timer{
+		s: pickShard(),
+		f: func(*time.Time) {
+			close(b.done)
+		},
+		reset: func() bool {
+			// Re-arm for future waiters if fired.
+			select {
+			case <-b.done:
+				b.done = make(chan struct{})
+				return true
+			default:
+				return false
+			}
+		},
+	}
+	addTimer(b.t, d)
+	return b
+}
+
+// Wait returns a channel which is closed as soon as the broadcaster fires.
+// If it already fired and was not reset since, the returned channel is
+// closed already.
+func (b *Broadcaster) Wait() <-chan struct{} {
+	if b.t == nil {
+		panic("timer: Wait called on uninitialized Broadcaster")
+	}
+	s := b.t.s
+	s.mutex.Lock()
+	c := b.done
+	s.mutex.Unlock()
+	return c
+}
+
+// Reset changes the broadcaster to fire after duration d.
+// Channels returned by Wait before the call, which were not closed yet,
+// are closed on the new fire as well. If the broadcaster already fired,
+// it is re-armed and subsequent calls to Wait return a new channel.
+// It returns true if the broadcaster had been active,
+// false if it had fired or been stopped.
+func (b *Broadcaster) Reset(d time.Duration) bool {
+	if b.t == nil {
+		panic("timer: Reset called on uninitialized Broadcaster")
+	}
+	return resetTimer(b.t, d, 0)
+}
+
+// Stop prevents the Broadcaster from firing. Waiters are not woken up
+// until the broadcaster is reset and fires.
+// It returns true if the call stops the broadcaster,
+// false if it has already fired or been stopped.
+func (b *Broadcaster) Stop() bool {
+	if b.t == nil {
+		panic("timer: Stop called on uninitialized Broadcaster")
+	}
+	return stopTimer(b.t)
+}
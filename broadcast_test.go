@@ -0,0 +1,78 @@
+package timer
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBroadcaster(t *testing.T) {
+	b := NewBroadcaster(50 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-b.Wait()
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("broadcaster: waiters were not woken up")
+	}
+
+	// A fired broadcaster stays fired until it is reset.
+	select {
+	case <-b.Wait():
+	default:
+		t.Fatalf("broadcaster: wait after fire blocks")
+	}
+
+	if b.Reset(50 * time.Millisecond) {
+		t.Errorf("broadcaster: was active is true")
+	}
+	c := b.Wait()
+	select {
+	case <-c:
+		t.Fatalf("broadcaster: re-armed broadcaster is fired")
+	default:
+	}
+	select {
+	case <-c:
+	case <-time.After(time.Second):
+		t.Fatalf("broadcaster: re-armed broadcaster did not fire")
+	}
+}
+
+func TestBroadcasterResetStop(t *testing.T) {
+	b := NewBroadcaster(time.Hour)
+	c := b.Wait()
+
+	// Current waiters are woken up by the new deadline.
+	if !b.Reset(10 * time.Millisecond) {
+		t.Errorf("broadcaster: was active is false")
+	}
+	select {
+	case <-c:
+	case <-time.After(time.Second):
+		t.Fatalf("broadcaster: waiter was not woken up after reset")
+	}
+
+	b.Reset(10 * time.Millisecond)
+	if !b.Stop() {
+		t.Errorf("broadcaster: was active is false")
+	}
+	select {
+	case <-b.Wait():
+		t.Errorf("broadcaster: stopped broadcaster fired")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,136 @@
+package timer
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// An OverflowPolicy decides what a CallbackPool does with a callback
+// if its queue is full.
+type OverflowPolicy int
+
+const (
+	// QueueOnOverflow queues the callback anyway. The queue grows
+	// without bound, but no callback is lost.
+	QueueOnOverflow OverflowPolicy = iota
+
+	// DropOnOverflow drops the callback, which is counted by Dropped.
+	DropOnOverflow
+)
+
+// A CallbackPool runs the functions passed to WithOnFire on a bounded set
+// of worker goroutines instead of the timer routine. Slow functions then
+// neither delay the fires of other timers nor each other, up to the number
+// of workers. A CallbackPool may be shared by many timers.
+//
+// A CallbackPool must be created with NewCallbackPool.
+type CallbackPool struct {
+	policy  OverflowPolicy
+	limit   int
+	dropped atomic.Uint64
+	wg      sync.WaitGroup
+
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	queue  []func()
+	closed bool
+}
+
+// NewCallbackPool creates a new CallbackPool and starts its workers.
+// Up to limit callbacks wait for a free worker, further callbacks are
+// handled by policy. Close must be called to release the workers.
+// NewCallbackPool panics if workers is less than one.
+func NewCallbackPool(workers, limit int, policy OverflowPolicy) *CallbackPool {
+	if workers < 1 {
+		panic("timer: NewCallbackPool called with less than one worker")
+	}
+	p := &CallbackPool{
+		policy: policy,
+		limit:  limit,
+	}
+	p.cond = sync.NewCond(&p.mutex)
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// WithCallbackPool runs the function passed to WithOnFire on the pool p
+// instead of the timer routine. The function is then called after the fire
+// was delivered, without the timer heap being locked, hence it may block
+// and may call methods of any Timer. Calls for successive fires of the same
+// Timer may overlap if the pool has more than one worker.
+// Panics are handled like those of AfterFunc functions,
+// see SetCallbackPanicHandler.
+func WithCallbackPool(p *CallbackPool) Option {
+	return func(o *options) {
+		o.callbackPool = p
+	}
+}
+
+// Dropped returns the number of callbacks dropped by DropOnOverflow.
+func (p *CallbackPool) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// Close runs the queued callbacks, waits until all workers exited and
+// returns. Callbacks submitted afterwards are dropped and counted.
+// Calling Close more than once is a no-op.
+func (p *CallbackPool) Close() {
+	p.mutex.Lock()
+	p.closed = true
+	p.cond.Broadcast()
+	p.mutex.Unlock()
+	p.wg.Wait()
+}
+
+// Queue f for a worker. This is called by the timer routines,
+// hence it must not block.
+func (p *CallbackPool) submit(f func()) {
+	p.mutex.Lock()
+	if p.closed || (p.policy == DropOnOverflow && len(p.queue) >= p.limit) {
+		p.mutex.Unlock()
+		p.dropped.Add(1)
+		return
+	}
+	p.queue = append(p.queue, f)
+	p.cond.Signal()
+	p.mutex.Unlock()
+}
+
+// The worker routine of the pool.
+func (p *CallbackPool) work() {
+	defer p.wg.Done()
+
+	p.mutex.Lock()
+	for {
+		for len(p.queue) == 0 && !p.closed {
+			p.cond.Wait()
+		}
+		if len(p.queue) == 0 {
+			p.mutex.Unlock()
+			return
+		}
+		f := p.queue[0]
+		p.queue[0] = nil
+		p.queue = p.queue[1:]
+		p.mutex.Unlock()
+
+		runCallback(f)
+		p.mutex.Lock()
+	}
+}
+
+// Return the fire function of a timer which calls onFire on the pool p
+// after calling f.
+func (p *CallbackPool) onFire(f func(t *time.Time), onFire func(time.Time)) func(t *time.Time) {
+	return func(t *time.Time) {
+		f(t)
+		v := *t
+		p.submit(func() {
+			onFire(v)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package timer
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCallbackPool(t *testing.T) {
+	p := NewCallbackPool(2, 10, QueueOnOverflow)
+	defer p.Close()
+
+	// A slow callback does not delay an unrelated timer of the same
+	// timer routine.
+	defer setShards(*shards.Load())
+	setShards(newShards(1))
+	release := make(chan struct{})
+	started := make(chan struct{})
+	NewTimerWithOptions(0, WithCallbackPool(p), WithOnFire(func(time.Time) {
+		close(started)
+		<-release
+	}))
+	<-started
+
+	start := time.Now()
+	timer := NewTimer(10 * time.Millisecond)
+	select {
+	case <-timer.C:
+		if d := time.Since(start); d > 500*time.Millisecond {
+			t.Errorf("callback pool: unrelated timer fired after %v", d)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("callback pool: unrelated timer was delayed by a slow callback")
+	}
+	close(release)
+
+	// The callback may call methods of its Timer.
+	var self *Timer
+	done := make(chan bool)
+	self = NewTimerWithOptions(time.Hour, WithCallbackPool(p), WithOnFire(func(time.Time) {
+		done <- self.Reset(time.Hour)
+	}))
+	self.Reset(0)
+	if <-done {
+		t.Errorf("callback pool: fired timer was active")
+	}
+	self.Stop()
+}
+
+func TestCallbackPoolOverflow(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	slow := func(time.Time) {
+		<-release
+		calls.Add(1)
+	}
+
+	for _, policy := range []OverflowPolicy{DropOnOverflow, QueueOnOverflow} {
+		calls.Store(0)
+		release = make(chan struct{})
+		p := NewCallbackPool(1, 2, policy)
+		clk := NewFakeClock(time.Unix(0, 0))
+		for i := 0; i < 10; i++ {
+			NewTimerWithOptions(time.Second, WithClock(clk), WithCallbackPool(p), WithOnFire(slow))
+		}
+		clk.Advance(time.Second)
+		close(release)
+
+		// Close runs the queued callbacks.
+		p.Close()
+		switch policy {
+		case DropOnOverflow:
+			// One callback is running, two are queued.
+			if n, d := calls.Load(), p.Dropped(); n+int32(d) != 10 || n < 2 || n > 3 {
+				t.Errorf("callback pool: drop policy ran %d and dropped %d callbacks", n, d)
+			}
+		case QueueOnOverflow:
+			if n, d := calls.Load(), p.Dropped(); n != 10 || d != 0 {
+				t.Errorf("callback pool: queue policy ran %d and dropped %d callbacks", n, d)
+			}
+		}
+	}
+}
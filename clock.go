@@ -0,0 +1,202 @@
+package timer
+
+import (
+	"sync"
+	"time"
+)
+
+// A Clock is the source of time for timers.
+// Timers driven by a clock are scheduled and fire according to its time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// AfterFunc calls f after duration d elapsed on the clock.
+	// The returned function prevents the call if it has not started yet and
+	// reports whether the call was prevented.
+	AfterFunc(d time.Duration, f func()) (stop func() bool)
+}
+
+// SystemClock is the Clock used by default. It is based on the system's
+// monotonic clock. All deadlines carry a monotonic clock reading and
+// durations are always computed from it, hence changes of the system wall
+// clock, for example by NTP or a manually set date, never cause a timer
+// to fire early or late.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) func() bool {
+	return time.AfterFunc(d, f).Stop
+}
+
+var (
+	clockMutex  sync.Mutex
+	clockShards = make(map[Clock]*shard)
+)
+
+// Return the shard of the custom clock clk.
+// The shard is created on first use and registered while it holds timers.
+func clockShard(clk Clock) *shard {
+	clockMutex.Lock()
+	defer clockMutex.Unlock()
+
+	s, ok := clockShards[clk]
+	if !ok {
+		s = &shard{clock: clk}
+		clockShards[clk] = s
+	}
+	return s
+}
+
+// Register the shard of a custom clock as it is about to hold timers.
+// Must be called with the shard lock held.
+func (s *shard) attachLocked() {
+	if s.attached || s.private {
+		return
+	}
+	s.attached = true
+	clockMutex.Lock()
+	if _, ok := clockShards[s.clock]; !ok {
+		clockShards[s.clock] = s
+	}
+	clockMutex.Unlock()
+	registerShard(s)
+}
+
+// Release the shard of a custom clock as soon as it is empty, hence the
+// package keeps no reference to clocks without timers, like the FakeClock
+// of a finished test. Timers of the shard attach it again when they are
+// scheduled. Timers created for the clock meanwhile might get another shard.
+// Must be called with the shard lock held.
+func (s *shard) detachLocked() {
+	if !s.attached || len(s.timers) > 0 {
+		return
+	}
+	s.attached = false
+	clockMutex.Lock()
+	if clockShards[s.clock] == s {
+		delete(clockShards, s.clock)
+	}
+	clockMutex.Unlock()
+	unregisterShard(s)
+}
+
+// Fire all expired timers and schedule the next wake up on the clock.
+// Shards driven by a custom clock have no timer routine and are woken
+// by the clock instead.
+func (s *shard) wake() {
+	s.mutex.Lock()
+	s.wakeLocked()
+	s.mutex.Unlock()
+}
+
+func (s *shard) wakeLocked() {
+	if s.stopWake != nil {
+		s.stopWake()
+		s.stopWake = nil
+	}
+	delta, ok := s.runTimersLocked(s.clock.Now())
+	if ok {
+		s.stopWake = s.clock.AfterFunc(delta, s.wake)
+	} else {
+		s.detachLocked()
+	}
+}
+
+// NewTimerWithClock creates a new Timer driven by the clock clk that will
+// send the clock's current time on its channel after at least duration d.
+// The clock must be comparable, as timers of the same clock share a heap.
+func NewTimerWithClock(clk Clock, d time.Duration) *Timer {
+	return NewTimerWithOptions(d, WithClock(clk))
+}
+
+// A FakeClock is a Clock whose time only moves if advanced manually.
+// Timers driven by a FakeClock fire synchronously during Advance, which
+// allows to test timeout logic deterministically and without sleeping.
+type FakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+	funcs []*fakeFunc
+}
+
+type fakeFunc struct {
+	when time.Time
+	f    func()
+}
+
+// NewFakeClock creates a new FakeClock set to the time now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the current time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// AfterFunc calls f during Advance, once the clock reached duration d
+// from now.
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) func() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	ff := &fakeFunc{when: c.now.Add(d), f: f}
+	c.funcs = append(c.funcs, ff)
+	return func() bool {
+		return c.remove(ff)
+	}
+}
+
+// Advance moves the clock forward by duration d. All functions and timers
+// which are due are called in the order of their deadlines, before Advance
+// returns. The clock's time is set to each deadline while it is called.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	end := c.now.Add(d)
+	c.mutex.Unlock()
+
+	for {
+		c.mutex.Lock()
+		var next *fakeFunc
+		for _, ff := range c.funcs {
+			if !ff.when.After(end) && (next == nil || ff.when.Before(next.when)) {
+				next = ff
+			}
+		}
+		if next == nil {
+			c.now = end
+			c.mutex.Unlock()
+			return
+		}
+		c.removeLocked(next)
+		if next.when.After(c.now) {
+			c.now = next.when
+		}
+		c.mutex.Unlock()
+
+		next.f()
+	}
+}
+
+func (c *FakeClock) remove(ff *fakeFunc) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.removeLocked(ff)
+}
+
+func (c *FakeClock) removeLocked(ff *fakeFunc) bool {
+	for i, f := range c.funcs {
+		if f == ff {
+			c.funcs = append(c.funcs[:i], c.funcs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package timer
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestFakeClock(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithClock(clk, time.Second)
+
+	clk.Advance(999 * time.Millisecond)
+	if len(timer.C) != 0 {
+		t.Fatalf("fake clock: timer fired early")
+	}
+	if r := timer.Remaining(); r != time.Millisecond {
+		t.Errorf("fake clock: invalid remaining duration %v", r)
+	}
+
+	clk.Advance(time.Millisecond)
+	if len(timer.C) != 1 {
+		t.Fatalf("fake clock: timer did not fire")
+	}
+	if v := <-timer.C; !v.Equal(time.Unix(1, 0)) {
+		t.Errorf("fake clock: invalid time value %v", v)
+	}
+}
+
+func TestFakeClockOrder(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	var timers []*Timer
+	for i := 5; i > 0; i-- {
+		timers = append(timers, NewTimerWithClock(clk, time.Duration(i)*time.Second))
+	}
+
+	// Each timer receives its own deadline.
+	clk.Advance(time.Hour)
+	for i, timer := range timers {
+		if v := <-timer.C; !v.Equal(time.Unix(int64(5-i), 0)) {
+			t.Errorf("fake clock: invalid time value %v", v)
+		}
+	}
+	if !clk.Now().Equal(time.Unix(3600, 0)) {
+		t.Errorf("fake clock: invalid time %v", clk.Now())
+	}
+}
+
+func TestFakeClockResetStop(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithClock(clk, time.Second)
+
+	if !timer.Reset(2 * time.Second) {
+		t.Errorf("fake clock: was active is false")
+	}
+	clk.Advance(time.Second)
+	if len(timer.C) != 0 {
+		t.Errorf("fake clock: reset timer fired early")
+	}
+	clk.Advance(time.Second)
+	if len(timer.C) != 1 {
+		t.Errorf("fake clock: reset timer did not fire")
+	}
+
+	timer.Reset(time.Second)
+	if !timer.Stop() {
+		t.Errorf("fake clock: was active is false")
+	}
+	clk.Advance(time.Hour)
+	if len(timer.C) != 0 {
+		t.Errorf("fake clock: stopped timer fired")
+	}
+
+	// A zero duration fires immediately.
+	timer.Reset(0)
+	if len(timer.C) != 1 {
+		t.Errorf("fake clock: zero timer did not fire")
+	}
+}
+
+func TestFakeClockRelease(t *testing.T) {
+	registered := func(s *shard) bool {
+		registryMutex.Lock()
+		_, ok := registry[s]
+		registryMutex.Unlock()
+		clockMutex.Lock()
+		defer clockMutex.Unlock()
+		return ok && clockShards[s.clock] == s
+	}
+
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithClock(clk, time.Second)
+	if !registered(timer.s) {
+		t.Fatalf("fake clock: shard with timers not registered")
+	}
+
+	// An empty shard is released, a reset registers it again.
+	timer.Stop()
+	if registered(timer.s) {
+		t.Errorf("fake clock: empty shard still registered after stop")
+	}
+	timer.Reset(time.Second)
+	if !registered(timer.s) {
+		t.Errorf("fake clock: shard not registered again by reset")
+	}
+	clk.Advance(time.Second)
+	if registered(timer.s) {
+		t.Errorf("fake clock: empty shard still registered after fire")
+	}
+}
+
+// Report whether t carries a monotonic clock reading.
+func hasMonotonic(t time.Time) bool {
+	return strings.Contains(t.String(), " m=")
+}
+
+func TestMonotonicDeadline(t *testing.T) {
+	timer := NewTimer(time.Hour)
+	defer timer.Stop()
+	if !hasMonotonic(timer.Deadline()) {
+		t.Errorf("monotonic: deadline has no monotonic clock reading")
+	}
+
+	// A wall clock only time is converted to the monotonic clock.
+	at := time.Now().Round(0).Add(time.Hour)
+	if hasMonotonic(at) {
+		t.Fatalf("monotonic: wall clock time has a monotonic clock reading")
+	}
+	timer.ResetAt(at)
+	if !hasMonotonic(timer.Deadline()) {
+		t.Errorf("monotonic: deadline has no monotonic clock reading")
+	}
+
+	timer = NewTimerAt(at)
+	defer timer.Stop()
+	if !hasMonotonic(timer.Deadline()) {
+		t.Errorf("monotonic: deadline has no monotonic clock reading")
+	}
+}
+
+// jumpClock is a FakeClock whose wall clock is shifted by jump, while its
+// monotonic clock advances unaffected, like after an NTP step.
+type jumpClock struct {
+	*FakeClock
+	jump time.Duration
+}
+
+func (c *jumpClock) Now() time.Time {
+	return shiftWall(c.FakeClock.Now(), c.jump)
+}
+
+// Shift the wall clock reading of t by d, keeping its monotonic clock
+// reading. This mirrors the layout of time.Time: with a monotonic clock
+// reading, the wall field holds the seconds since 1885 above bit 30.
+func shiftWall(t time.Time, d time.Duration) time.Time {
+	type timeLayout struct {
+		wall uint64
+		ext  int64
+		loc  *time.Location
+	}
+	l := (*timeLayout)(unsafe.Pointer(&t))
+	l.wall = uint64(int64(l.wall) + int64(d/time.Second)<<30)
+	return t
+}
+
+func TestWallClockJump(t *testing.T) {
+	// Times of the fake clock carry a monotonic clock reading.
+	start := time.Now()
+	if shifted := shiftWall(start, -time.Hour); start.Sub(shifted) != 0 || start.Unix()-shifted.Unix() != 3600 {
+		t.Skip("wall clock jump: unsupported time.Time layout")
+	}
+
+	for _, jump := range []time.Duration{-time.Hour, time.Hour} {
+		clk := &jumpClock{FakeClock: NewFakeClock(start)}
+		timer := NewTimerWithClock(clk, time.Second)
+
+		// The wall clock jumps, but the monotonic clock keeps advancing.
+		clk.jump = jump
+		if clk.Now().Unix()-start.Unix() != int64(jump/time.Second) {
+			t.Fatalf("wall clock jump: wall clock did not jump")
+		}
+
+		clk.Advance(999 * time.Millisecond)
+		if len(timer.C) != 0 {
+			t.Errorf("wall clock jump %v: timer fired early", jump)
+		}
+		if r := timer.Remaining(); r != time.Millisecond {
+			t.Errorf("wall clock jump %v: invalid remaining duration %v", jump, r)
+		}
+		clk.Advance(time.Millisecond)
+		if len(timer.C) != 1 {
+			t.Errorf("wall clock jump %v: timer did not fire after one second", jump)
+		}
+	}
+}
+
+// delayClock is a FakeClock which wakes timers late by delay.
+type delayClock struct {
+	*FakeClock
+	delay time.Duration
+}
+
+func (c *delayClock) AfterFunc(d time.Duration, f func()) func() bool {
+	return c.FakeClock.AfterFunc(d+c.delay, f)
+}
+
+// frozenClock is a FakeClock which never wakes its timers,
+// hence expired timers stay in the heap until the next reschedule.
+type frozenClock struct {
+	*FakeClock
+}
+
+func (c *frozenClock) AfterFunc(time.Duration, func()) func() bool {
+	return func() bool { return true }
+}
@@ -0,0 +1,103 @@
+package timer
+
+import (
+	"context"
+	"time"
+)
+
+// WithTimeout returns a copy of the parent context which is cancelled
+// as soon as the returned Timer fires after duration d.
+// Unlike context.WithTimeout, the deadline is not fixed: Reset the Timer
+// to push the cancellation out and Stop the Timer to keep the context alive
+// until the parent is cancelled.
+//
+// The Timer is created with AfterFunc and its C field is nil. It is stopped
+// as soon as the returned context is done, whether by the parent, by the
+// Timer or by cancel. No goroutine is bound to the context meanwhile.
+// Like for context.WithCancel, call cancel to release the context's
+// resources as soon as the work is done.
+//
+// If the Timer fires, the context's Err returns context.Canceled and
+// context.Cause returns ErrTimeout. If cancel is called first, Cause returns
+// context.Canceled, and if the parent is done first, the parent's cause.
+// Deadline reports no deadline, because the Timer can be reset at any time.
+func WithTimeout(parent context.Context, d time.Duration) (context.Context, *Timer, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	t := AfterFunc(d, func() {
+		cancel(ErrTimeout)
+	})
+	context.AfterFunc(ctx, func() {
+		t.Stop()
+	})
+	return ctx, t, func() { cancel(context.Canceled) }
+}
+
+// AfterFuncContext waits for the duration to elapse and then calls f with
+// ctx in its own goroutine, like AfterFunc. f should watch ctx to abort its
+// work if ctx is cancelled while it runs.
+//
+// If ctx is done before the timer fires, the Timer is stopped and f is not
+// called. A call which races with the cancellation checks ctx right before
+// f is started and is skipped as well. The Timer stays bound to ctx across
+// Reset until ctx is done, like a context.AfterFunc registration.
+// ResetFunc replaces the call of f and drops the check of ctx.
+func AfterFuncContext(ctx context.Context, d time.Duration, f func(ctx context.Context)) *Timer {
+	t := AfterFunc(d, func() {
+		if ctx.Err() == nil {
+			f(ctx)
+		}
+	})
+	context.AfterFunc(ctx, func() {
+		t.Stop()
+	})
+	return t
+}
+
+// NewTimerFromContext creates a new Timer which fires at the deadline of ctx
+// and returns true. If the deadline already passed, the timer fires
+// immediately like NewTimer(0). If ctx has no deadline, a stopped Timer
+// is returned with false, which never fires unless it is reset.
+//
+// The timer is not stopped if ctx is cancelled before its deadline.
+func NewTimerFromContext(ctx context.Context) (*Timer, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return NewStoppedTimer(), false
+	}
+	return NewTimerAt(deadline), true
+}
+
+// WaitContext blocks until the timer fires and returns the received time.
+// If ctx is done first, the timer is stopped, its channel is drained and
+// ctx.Err() is returned.
+// A value which is already pending in t.C is returned immediately.
+func (t *Timer) WaitContext(ctx context.Context) (time.Time, error) {
+	checkStrictReceive(t, "WaitContext")
+
+	// Prefer a pending value over a done context.
+	select {
+	case v := <-t.C:
+		return v, nil
+	default:
+	}
+
+	select {
+	case v := <-t.C:
+		return v, nil
+	case <-ctx.Done():
+		t.StopAndDrain()
+		return time.Time{}, ctx.Err()
+	}
+}
+
+// Err returns ErrTimeout if the timer fired since it was last scheduled
+// and nil otherwise, like the Err method of a context. It reports the
+// sticky state of Fired and does not receive from t.C.
+// Together with WaitContext this turns a timer into a deadline for code
+// which returns errors.
+func (t *Timer) Err() error {
+	if timerFired(t) {
+		return ErrTimeout
+	}
+	return nil
+}
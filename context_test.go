@@ -0,0 +1,223 @@
+package timer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithTimeout(t *testing.T) {
+	start := time.Now()
+	ctx, timer, cancel := WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	timer.Reset(300 * time.Millisecond)
+
+	<-ctx.Done()
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond || elapsed > 400*time.Millisecond {
+		t.Errorf("with timeout: took %v", elapsed)
+	}
+	if ctx.Err() != context.Canceled {
+		t.Errorf("with timeout: invalid context error: %v", ctx.Err())
+	}
+	if err := context.Cause(ctx); err != ErrTimeout {
+		t.Errorf("with timeout: invalid cause: %v", err)
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Errorf("with timeout: context has a deadline")
+	}
+}
+
+func TestWithTimeoutStop(t *testing.T) {
+	ctx, timer, cancel := WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if !timer.Stop() {
+		t.Errorf("with timeout: was active is false")
+	}
+
+	select {
+	case <-ctx.Done():
+		t.Errorf("with timeout: context cancelled after stop")
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestWithTimeoutParent(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, timer, cancel := WithTimeout(parent, time.Hour)
+	defer cancel()
+
+	cancelParent()
+	select {
+	case <-ctx.Done():
+	case <-time.After(100 * time.Millisecond):
+		t.Errorf("with timeout: context not cancelled by parent")
+	}
+	if err := context.Cause(ctx); err != context.Canceled {
+		t.Errorf("with timeout: invalid cause: %v", err)
+	}
+
+	// The timer is released with the parent.
+	waitInactive(t, timer)
+}
+
+func TestWithTimeoutCancel(t *testing.T) {
+	ctx, timer, cancel := WithTimeout(context.Background(), time.Hour)
+	cancel()
+	if ctx.Err() != context.Canceled {
+		t.Errorf("with timeout: invalid context error: %v", ctx.Err())
+	}
+	if err := context.Cause(ctx); err != context.Canceled {
+		t.Errorf("with timeout: invalid cause: %v", err)
+	}
+	waitInactive(t, timer)
+}
+
+// Wait until the timer is stopped. The stop runs in its own goroutine.
+func waitInactive(t *testing.T, timer *Timer) {
+	for i := 0; i < 100 && timer.Active(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if timer.Active() {
+		t.Errorf("timer was not stopped")
+	}
+}
+
+func TestAfterFuncContext(t *testing.T) {
+	// Cancel before fire.
+	ctx, cancel := context.WithCancel(context.Background())
+	called := make(chan context.Context, 1)
+	f := func(ctx context.Context) {
+		called <- ctx
+	}
+	timer := AfterFuncContext(ctx, 100*time.Millisecond, f)
+	cancel()
+	select {
+	case <-called:
+		t.Errorf("after func context: called after cancel")
+	case <-time.After(300 * time.Millisecond):
+	}
+	if timer.Active() {
+		t.Errorf("after func context: timer not stopped by cancel")
+	}
+
+	// An already cancelled context never calls f.
+	AfterFuncContext(ctx, 0, f)
+	select {
+	case <-called:
+		t.Errorf("after func context: called with cancelled context")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Fire, then cancel.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	aborted := make(chan error, 1)
+	timer = AfterFuncContext(ctx, 10*time.Millisecond, func(ctx context.Context) {
+		if ctx.Err() != nil {
+			t.Errorf("after func context: called with done context")
+		}
+		<-ctx.Done()
+		aborted <- ctx.Err()
+	})
+	time.Sleep(100 * time.Millisecond)
+	if !timer.Fired() {
+		t.Fatalf("after func context: timer did not fire")
+	}
+	cancel()
+	select {
+	case err := <-aborted:
+		if err != context.Canceled {
+			t.Errorf("after func context: invalid context error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("after func context: callback not aborted by cancel")
+	}
+}
+
+func TestWaitContext(t *testing.T) {
+	timer := NewTimer(100 * time.Millisecond)
+	v, err := timer.WaitContext(context.Background())
+	if err != nil || v.IsZero() {
+		t.Errorf("wait context: invalid result: %v %v", v, err)
+	}
+
+	// A pending value is returned even if the context is done.
+	timer.Reset(0)
+	time.Sleep(50 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if len(timer.C) != 1 {
+		t.Fatalf("wait context: channel should be filled")
+	}
+	if v, err = timer.WaitContext(ctx); err != nil || v.IsZero() {
+		t.Errorf("wait context: invalid result: %v %v", v, err)
+	}
+
+	// Cancellation stops the timer.
+	timer.Reset(time.Hour)
+	v, err = timer.WaitContext(ctx)
+	if err != context.Canceled || !v.IsZero() {
+		t.Errorf("wait context: invalid result: %v %v", v, err)
+	}
+	if timer.Active() {
+		t.Errorf("wait context: timer was not stopped")
+	}
+}
+
+func TestNewTimerFromContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	timer, ok := NewTimerFromContext(ctx)
+	if !ok {
+		t.Fatalf("timer from context: no deadline")
+	}
+	deadline, _ := ctx.Deadline()
+	select {
+	case v := <-timer.C:
+		if v.Before(deadline) {
+			t.Errorf("timer from context: fired before the deadline")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timer from context: timer did not fire")
+	}
+
+	// An expired deadline fires immediately.
+	timer, ok = NewTimerFromContext(ctx)
+	if !ok {
+		t.Fatalf("timer from context: no deadline")
+	}
+	select {
+	case <-timer.C:
+	case <-time.After(100 * time.Millisecond):
+		t.Fatalf("timer from context: expired timer did not fire")
+	}
+
+	timer, ok = NewTimerFromContext(context.Background())
+	if ok || timer.Active() {
+		t.Errorf("timer from context: timer without deadline is active")
+	}
+}
+
+func TestErr(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithClock(clk, time.Second)
+	if err := timer.Err(); err != nil {
+		t.Errorf("err: active timer returned %v", err)
+	}
+	clk.Advance(time.Second)
+	if err := timer.Err(); err != ErrTimeout {
+		t.Errorf("err: fired timer returned %v", err)
+	}
+	if len(timer.C) != 1 {
+		t.Errorf("err: value was received from the channel")
+	}
+	if ErrTimeout.Error() != "timer: deadline exceeded" {
+		t.Errorf("err: invalid message %q", ErrTimeout)
+	}
+
+	timer.Reset(time.Second)
+	if err := timer.Err(); err != nil {
+		t.Errorf("err: reset timer returned %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package timer
+
+import (
+	"fmt"
+	"time"
+)
+
+// Heap exposes the bare timer heap to the benchmarks, without locking,
+// tagging and timer routine.
+type Heap struct {
+	s shard
+}
+
+// Len returns the number of timers in the heap.
+func (h *Heap) Len() int {
+	return len(h.s.timers)
+}
+
+// Push inserts a new timer with the wake up time when.
+func (h *Heap) Push(when time.Time) *Timer {
+	t := &Timer{when: when}
+	h.s.heapPush(t)
+	return t
+}
+
+// Remove removes timer t from the heap.
+func (h *Heap) Remove(t *Timer) {
+	h.s.heapRemove(t)
+}
+
+// Root returns the timer which expires next.
+func (h *Heap) Root() *Timer {
+	return h.s.timers[0]
+}
+
+// Last returns the last timer in the heap array, which is a leaf.
+func (h *Heap) Last() *Timer {
+	return h.s.timers[len(h.s.timers)-1]
+}
+
+// When returns the wake up time of timer t.
+func (h *Heap) When(t *Timer) time.Time {
+	return t.when
+}
+
+// Check verifies the heap invariant, see checkHeapInvariant.
+func (h *Heap) Check() error {
+	return h.s.checkHeapLocked()
+}
+
+// checkHeapInvariant verifies the heaps and timing wheels of all shards.
+// In a heap, no timer fires before its parent and the index of each timer
+// matches its position. In a wheel, the bucket and index of each timer
+// match its position.
+func checkHeapInvariant() error {
+	for _, s := range allShards() {
+		s.mutex.Lock()
+		err := s.checkHeapLocked()
+		s.mutex.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *shard) checkHeapLocked() error {
+	if s.wheel != nil {
+		n := 0
+		for b, bucket := range s.wheel.buckets {
+			for i, t := range bucket {
+				if t.b != b || t.i != i {
+					return fmt.Errorf("wheel: timer at %v/%v has position %v/%v", b, i, t.b, t.i)
+				}
+				n++
+			}
+		}
+		if n != s.wheel.n {
+			return fmt.Errorf("wheel: %v timers counted as %v", n, s.wheel.n)
+		}
+		return nil
+	}
+	for i, t := range s.timers {
+		if t.i != i {
+			return fmt.Errorf("heap: timer at %v has index %v", i, t.i)
+		}
+		if t.s != s && t.s != nil {
+			return fmt.Errorf("heap: timer at %v belongs to another shard", i)
+		}
+		if p := (i - 1) / 4; i > 0 && t.before(s.timers[p]) {
+			return fmt.Errorf("heap: timer at %v fires before its parent at %v", i, p)
+		}
+	}
+	return nil
+}
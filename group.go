@@ -0,0 +1,245 @@
+package timer
+
+import (
+	"sync"
+	"time"
+)
+
+// An Event is delivered by a Group each time one of its timers fires.
+type Event struct {
+	Timer *Timer
+	Time  time.Time
+}
+
+// A Group fans in the fires of many timers into a single channel
+// without a goroutine per timer. The fires are delivered on C in the
+// order of their fire times.
+//
+// The timers of a group are tagged with the group like by NewTimerTagged,
+// hence StopByTag(g) stops them and they take their turns together with
+// SetFairTags.
+//
+// A Group must be created with NewGroup.
+type Group struct {
+	C <-chan Event // The channel on which the events are delivered.
+
+	f *fanIn[Event]
+
+	mutex   sync.Mutex
+	stopped bool
+}
+
+// NewGroup creates a new Group and starts its delivery routine.
+// Stop must be called to release the routine.
+func NewGroup() *Group {
+	f := newFanIn[Event]()
+	return &Group{
+		C: f.c,
+		f: f,
+	}
+}
+
+// Add creates a new Timer in the group that fires after at least duration d.
+// The fire is delivered as an Event on g.C. The returned Timer's C field is nil.
+//
+// Stop and Reset are safe to use on the returned Timer. Both remove an
+// undelivered event of the timer from the group.
+// The group references the timer only while it is scheduled.
+func (g *Group) Add(d time.Duration) *Timer {
+	t := g.f.newTimer(func(t *Timer, now time.Time) Event {
+		return Event{Timer: t, Time: now}
+	})
+	t.tag = g
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.stopped {
+		panic("timer: Add called on stopped Group")
+	}
+	addTimer(t, d)
+	return t
+}
+
+// Stop stops all timers of the group, drops the undelivered events
+// and closes g.C. Stop must not be called concurrently with Add.
+// Calling Stop more than once is a no-op.
+func (g *Group) Stop() {
+	g.mutex.Lock()
+	if g.stopped {
+		g.mutex.Unlock()
+		return
+	}
+	g.stopped = true
+	g.mutex.Unlock()
+
+	StopByTag(g)
+	g.f.close()
+}
+
+// A fanIn queues the fires of its timers ordered by their fire time
+// and delivers them by a single routine on an unbuffered channel.
+type fanIn[E any] struct {
+	c      chan E
+	notify chan struct{}
+	done   chan struct{}
+	exited chan struct{}
+
+	mutex  sync.Mutex
+	queue  []fanInEntry[E]
+	seq    uint64 // Number of the last queued entry.
+	closed bool
+}
+
+type fanInEntry[E any] struct {
+	key  interface{} // Timer of the entry, removed by its Stop and Reset.
+	when time.Time
+	seq  uint64 // Identifies the entry while it is offered.
+	e    E
+}
+
+// Create a fanIn and start its delivery routine.
+func newFanIn[E any]() *fanIn[E] {
+	f := &fanIn[E]{
+		c:      make(chan E),
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	go f.deliver()
+	return f
+}
+
+// Create a stopped Timer without channel, whose fires are queued
+// as the value returned by event. Stop and Reset remove the undelivered
+// fires of the timer.
+func (f *fanIn[E]) newTimer(event func(t *Timer, now time.Time) E) *Timer {
+	t := &Timer{s: pickShard()}
+	t.f = func(now *time.Time) {
+		f.push(fanInEntry[E]{key: t, when: *now, e: event(t, *now)})
+	}
+	t.reset = func() bool {
+		return f.remove(t)
+	}
+	t.stop = func() {
+		f.remove(t)
+	}
+	return t
+}
+
+// Drop the undelivered fires, stop the delivery routine and close the channel.
+func (f *fanIn[E]) close() {
+	f.mutex.Lock()
+	f.closed = true
+	f.queue = nil
+	f.mutex.Unlock()
+
+	close(f.done)
+	<-f.exited
+	close(f.c)
+}
+
+// Queue the entry e ordered by its fire time.
+// This is called by the timer routines, hence it must not block.
+func (f *fanIn[E]) push(e fanInEntry[E]) {
+	f.mutex.Lock()
+	if f.closed {
+		f.mutex.Unlock()
+		return
+	}
+	f.seq++
+	e.seq = f.seq
+
+	// Fires are mostly in order, so search from the back.
+	i := len(f.queue)
+	for i > 0 && f.queue[i-1].when.After(e.when) {
+		i--
+	}
+	f.queue = append(f.queue, fanInEntry[E]{})
+	copy(f.queue[i+1:], f.queue[i:])
+	f.queue[i] = e
+	f.mutex.Unlock()
+
+	f.wake()
+}
+
+// Remove the undelivered fires of the timer key.
+// It returns true if a fire was removed.
+func (f *fanIn[E]) remove(key interface{}) (b bool) {
+	f.mutex.Lock()
+	q := f.queue[:0]
+	for _, e := range f.queue {
+		if e.key == key {
+			b = true
+			continue
+		}
+		q = append(q, e)
+	}
+	for i := len(q); i < len(f.queue); i++ {
+		f.queue[i] = fanInEntry[E]{}
+	}
+	f.queue = q
+	f.mutex.Unlock()
+
+	if b {
+		// Wake the delivery routine which might be offering the fire.
+		f.wake()
+	}
+	return
+}
+
+// Wake the delivery routine without blocking.
+func (f *fanIn[E]) wake() {
+	select {
+	case f.notify <- struct{}{}:
+	default:
+	}
+}
+
+// The delivery routine of the fanIn.
+func (f *fanIn[E]) deliver() {
+	defer close(f.exited)
+
+	for {
+		f.mutex.Lock()
+		if len(f.queue) == 0 {
+			f.mutex.Unlock()
+			select {
+			case <-f.notify:
+				continue
+			case <-f.done:
+				return
+			}
+		}
+		e := f.queue[0]
+		f.mutex.Unlock()
+
+		select {
+		case f.c <- e.e:
+			// Remove the delivered fire, unless it was removed by a Stop
+			// or Reset in the meantime. It is not necessarily the head
+			// anymore: the timers of other shards fire with their own
+			// time and might have queued an earlier fire meanwhile.
+			f.mutex.Lock()
+			for i := range f.queue {
+				if f.queue[i].seq != e.seq {
+					continue
+				}
+				if i == 0 {
+					f.queue[0] = fanInEntry[E]{}
+					f.queue = f.queue[1:]
+				} else {
+					copy(f.queue[i:], f.queue[i+1:])
+					f.queue[len(f.queue)-1] = fanInEntry[E]{}
+					f.queue = f.queue[:len(f.queue)-1]
+				}
+				break
+			}
+			f.mutex.Unlock()
+		case <-f.notify:
+			// The head might have changed.
+		case <-f.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package timer
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestGroup(t *testing.T) {
+	g := NewGroup()
+	defer g.Stop()
+
+	t3 := g.Add(30 * time.Millisecond)
+	t1 := g.Add(10 * time.Millisecond)
+	t2 := g.Add(20 * time.Millisecond)
+
+	for i, want := range []*Timer{t1, t2, t3} {
+		select {
+		case e := <-g.C:
+			if e.Timer != want {
+				t.Fatalf("group: event %d delivered out of order", i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("group: event %d not delivered", i)
+		}
+	}
+}
+
+func TestGroupOrder(t *testing.T) {
+	g := NewGroup()
+	defer g.Stop()
+
+	const n = 10000
+	for i := 0; i < n; i++ {
+		g.Add(time.Duration(n-i) * time.Microsecond)
+	}
+
+	// Let all timers fire before receiving.
+	time.Sleep(100 * time.Millisecond)
+
+	var last time.Time
+	for i := 0; i < n; i++ {
+		e := <-g.C
+		if e.Time.Before(last) {
+			t.Fatalf("group: event %d delivered out of order", i)
+		}
+		last = e.Time
+	}
+}
+
+func TestGroupReset(t *testing.T) {
+	g := NewGroup()
+	defer g.Stop()
+
+	timer := g.Add(0)
+	time.Sleep(50 * time.Millisecond)
+
+	timer.Reset(time.Hour)
+	select {
+	case <-g.C:
+		t.Fatalf("group: removed event delivered")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestGroupTimerStop(t *testing.T) {
+	g := NewGroup()
+	defer g.Stop()
+
+	timer := g.Add(0)
+	time.Sleep(50 * time.Millisecond)
+
+	timer.Stop()
+	select {
+	case <-g.C:
+		t.Fatalf("group: event of stopped timer delivered")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestGroupStop(t *testing.T) {
+	g := NewGroup()
+	timer := g.Add(time.Hour)
+	g.Add(0)
+	time.Sleep(50 * time.Millisecond)
+
+	g.Stop()
+	g.Stop()
+
+	if timer.Active() {
+		t.Errorf("group: timer still active after Stop")
+	}
+	if _, ok := <-g.C; ok {
+		t.Errorf("group: channel not closed after Stop")
+	}
+}
+
+func TestGroupRelease(t *testing.T) {
+	g := NewGroup()
+	defer g.Stop()
+
+	const n = 100
+	var timers []*Timer
+	for i := 0; i < n; i++ {
+		timers = append(timers, g.Add(0))
+		g.Add(time.Hour).Stop()
+	}
+	for i := 0; i < n; i++ {
+		<-g.C
+	}
+
+	// Fired and stopped timers are not referenced by the group. A fire is
+	// delivered before its timer routine releases the timer.
+	var l int
+	for i := 0; i < 100; i++ {
+		tagMutex.Lock()
+		l = len(tags[g])
+		tagMutex.Unlock()
+		if l == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if l != 0 {
+		t.Errorf("group: %d fired or stopped timers still referenced", l)
+	}
+
+	// A fired timer which is reset is stopped with the group.
+	timers[0].Reset(time.Hour)
+	g.Stop()
+	if timers[0].Active() {
+		t.Errorf("group: reset timer still active after Stop")
+	}
+}
+
+// The timer routines of the shards fire with their own time, hence a fire
+// might be queued before the fire which is offered on C meanwhile.
+func TestFanInConcurrentPush(t *testing.T) {
+	f := newFanIn[int]()
+	defer f.close()
+
+	const routines, n = 4, 2000
+	base := time.Now()
+	for r := 0; r < routines; r++ {
+		go func(r int) {
+			for i := 0; i < n; i++ {
+				id := r*n + i
+				f.push(fanInEntry[int]{
+					when: base.Add(time.Duration(rand.Intn(n)) * time.Microsecond),
+					e:    id,
+				})
+			}
+		}(r)
+	}
+
+	seen := make([]bool, routines*n)
+	for i := 0; i < routines*n; i++ {
+		select {
+		case id := <-f.c:
+			if seen[id] {
+				t.Fatalf("fan in: fire %d delivered twice", id)
+			}
+			seen[id] = true
+		case <-time.After(time.Second):
+			t.Fatalf("fan in: only %d of %d fires delivered", i, routines*n)
+		}
+	}
+	select {
+	case id := <-f.c:
+		t.Fatalf("fan in: fire %d delivered twice", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
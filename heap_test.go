@@ -0,0 +1,104 @@
+package timer_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/desertbit/timer"
+)
+
+var heapSizes = []int{1000, 10000, 100000}
+
+// Create a heap with n timers expiring one nanosecond apart from base.
+func newHeap(n int, base time.Time) *timer.Heap {
+	h := &timer.Heap{}
+	for i := 0; i < n; i++ {
+		h.Push(base.Add(time.Duration(i)))
+	}
+	return h
+}
+
+func benchmarkHeap(b *testing.B, f func(b *testing.B, n int)) {
+	for _, n := range heapSizes {
+		b.Run(fmt.Sprintf("timers=%d", n), func(b *testing.B) {
+			f(b, n)
+		})
+	}
+}
+
+// Push timers expiring after all others, which stay at the leaves.
+func BenchmarkHeapPushBest(b *testing.B) {
+	benchmarkHeap(b, func(b *testing.B, n int) {
+		base := time.Now()
+		h := newHeap(n, base)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			h.Push(base.Add(time.Duration(n + i)))
+			h.Remove(h.Last())
+		}
+	})
+}
+
+// Push timers expiring before all others, which are sifted up to the root.
+func BenchmarkHeapPushWorst(b *testing.B) {
+	benchmarkHeap(b, func(b *testing.B, n int) {
+		base := time.Now()
+		h := newHeap(n, base)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			h.Push(base.Add(-time.Duration(i + 1)))
+			h.Remove(h.Last())
+		}
+	})
+}
+
+// Remove the last timer, which requires no sift.
+func BenchmarkHeapRemoveBest(b *testing.B) {
+	benchmarkHeap(b, func(b *testing.B, n int) {
+		base := time.Now()
+		h := newHeap(n, base)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			h.Remove(h.Last())
+			h.Push(base.Add(time.Duration(n + i)))
+		}
+	})
+}
+
+// Remove the root, which sifts the last timer down to the leaves.
+func BenchmarkHeapRemoveWorst(b *testing.B) {
+	benchmarkHeap(b, func(b *testing.B, n int) {
+		base := time.Now()
+		h := newHeap(n, base)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			h.Remove(h.Root())
+			h.Push(base.Add(time.Duration(n + i)))
+		}
+	})
+}
+
+func TestHeap(t *testing.T) {
+	base := time.Now()
+	h := newHeap(1000, base)
+	for i := 0; i < 1000; i += 3 {
+		h.Push(base.Add(-time.Duration(i)))
+	}
+	if err := h.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	last := h.Root()
+	for h.Len() > 0 {
+		root := h.Root()
+		if h.When(root).Before(h.When(last)) {
+			t.Fatalf("heap: timers removed out of order")
+		}
+		h.Remove(root)
+		if err := h.Check(); err != nil {
+			t.Fatal(err)
+		}
+		last = root
+	}
+}
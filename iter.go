@@ -0,0 +1,49 @@
+//go:build go1.23
+
+package timer
+
+import (
+	"context"
+	"iter"
+	"time"
+)
+
+// Fires returns an iterator over the values received from t.C, which is
+// mostly useful for periodic timers:
+//
+//	for now := range t.Fires(ctx) {
+//		...
+//	}
+//
+// The iteration ends when ctx is done or t.C is closed. The timer is
+// stopped and drained as soon as the iteration ends, including a break
+// out of the loop, hence it does not keep firing unobserved.
+func (t *Timer) Fires(ctx context.Context) iter.Seq[time.Time] {
+	if t.f == nil {
+		panic("timer: Fires called on uninitialized Timer")
+	}
+	checkStrictReceive(t, "Fires")
+	return func(yield func(time.Time) bool) {
+		defer t.StopAndDrain()
+
+		for {
+			select {
+			case v, ok := <-t.C:
+				if !ok || !yield(v) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// Fires returns an iterator over the ticks of the ticker, like Timer.Fires.
+// The ticker is stopped as soon as the iteration ends.
+func (t *Ticker) Fires(ctx context.Context) iter.Seq[time.Time] {
+	if t.t == nil {
+		panic("timer: Fires called on uninitialized Ticker")
+	}
+	return t.t.Fires(ctx)
+}
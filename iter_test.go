@@ -0,0 +1,52 @@
+//go:build go1.23
+
+package timer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFires(t *testing.T) {
+	ticker := NewTicker(10 * time.Millisecond)
+
+	var n int
+	for range ticker.Fires(context.Background()) {
+		n++
+		if n == 3 {
+			break
+		}
+	}
+	if ticker.t.Active() {
+		t.Errorf("fires: ticker still active after break")
+	}
+	if len(ticker.C) != 0 {
+		t.Errorf("fires: ticker not drained after break")
+	}
+}
+
+func TestFiresContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	timer := NewTimer(10 * time.Millisecond)
+	var n int
+	for range timer.Fires(ctx) {
+		n++
+	}
+	if n != 1 {
+		t.Errorf("fires: invalid fire count %v", n)
+	}
+	if ctx.Err() == nil {
+		t.Errorf("fires: iteration ended before the context was done")
+	}
+}
+
+func TestFiresClosed(t *testing.T) {
+	timer := NewTimerWithOptions(time.Hour, WithCloseOnStop())
+	timer.Stop()
+	for range timer.Fires(context.Background()) {
+		t.Fatalf("fires: closed channel yielded a value")
+	}
+}
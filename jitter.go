@@ -0,0 +1,56 @@
+package timer
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	jitterMutex sync.Mutex
+	jitterRand  = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// SetJitterSource sets the random source used to compute jitter.
+// This is useful to make jitter deterministic in tests.
+func SetJitterSource(src rand.Source) {
+	jitterMutex.Lock()
+	jitterRand = rand.New(src)
+	jitterMutex.Unlock()
+}
+
+// Return d with a random offset in [-jitter, +jitter] applied.
+// The result is clamped to zero.
+func jitterDuration(d, jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return d
+	}
+	if jitter > math.MaxInt64/2 {
+		jitter = math.MaxInt64 / 2
+	}
+
+	jitterMutex.Lock()
+	offset := time.Duration(jitterRand.Int63n(int64(2*jitter)+1)) - jitter
+	jitterMutex.Unlock()
+
+	if offset > 0 && d > math.MaxInt64-offset {
+		return math.MaxInt64
+	}
+	if d += offset; d < 0 {
+		return 0
+	}
+	return d
+}
+
+// ResetJitter changes the timer to expire after duration d plus a random
+// offset in [-jitter, +jitter]. The resulting duration is clamped to zero.
+// It behaves like Reset and clears the channel t.C.
+// Use it to avoid many timers firing in sync.
+func (t *Timer) ResetJitter(d, jitter time.Duration) bool {
+	if t.f == nil {
+		panic("timer: ResetJitter called on uninitialized Timer")
+	}
+	checkStrict(t, "ResetJitter")
+	return resetTimer(t, jitterDuration(d, jitter), 0)
+}
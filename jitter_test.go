@@ -0,0 +1,46 @@
+package timer
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestJitterDuration(t *testing.T) {
+	SetJitterSource(rand.NewSource(1))
+
+	for i := 0; i < 1000; i++ {
+		d := jitterDuration(time.Second, 100*time.Millisecond)
+		if d < 900*time.Millisecond || d > 1100*time.Millisecond {
+			t.Fatalf("jitter: duration out of range: %v", d)
+		}
+	}
+
+	// Clamped to zero.
+	for i := 0; i < 1000; i++ {
+		if d := jitterDuration(0, time.Second); d < 0 {
+			t.Fatalf("jitter: negative duration: %v", d)
+		}
+	}
+
+	if d := jitterDuration(time.Second, 0); d != time.Second {
+		t.Errorf("jitter: invalid duration without jitter: %v", d)
+	}
+}
+
+func TestResetJitter(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithClock(clk, time.Hour)
+
+	if !timer.ResetJitter(time.Second, 100*time.Millisecond) {
+		t.Errorf("reset jitter: was active is false")
+	}
+	clk.Advance(899 * time.Millisecond)
+	if len(timer.C) != 0 {
+		t.Errorf("reset jitter: timer fired early")
+	}
+	clk.Advance(201 * time.Millisecond)
+	if len(timer.C) != 1 {
+		t.Errorf("reset jitter: timer did not fire")
+	}
+}
@@ -0,0 +1,75 @@
+package timer
+
+import (
+	"time"
+)
+
+// A JitterTicker delivers ticks like a Ticker, but each tick is offset by a
+// random jitter. The ticks stay anchored to the start: the nth tick is sent
+// at start + n*d plus its own jitter, hence neither the jitter nor a slow
+// receiver make the ticker drift.
+//
+// A JitterTicker must be created with NewJitterTicker.
+type JitterTicker struct {
+	C <-chan time.Time // The channel on which the ticks are delivered.
+
+	t      *Timer
+	start  time.Time
+	d      time.Duration
+	jitter time.Duration
+	n      time.Duration // Index of the scheduled tick, accessed in a locked context.
+}
+
+// NewJitterTicker returns a new JitterTicker which sends the current time on
+// its channel at start + n*d + j for n = 1, 2, ..., where start is the time
+// of the call and j is a random offset in [-jitter, +jitter] per tick.
+// The jitter is limited to d/2, which keeps the ticks in order. Ticks are
+// dropped for slow receivers like for a Ticker and ticks which were missed
+// are skipped. The duration d must be greater than zero; if not,
+// NewJitterTicker will panic. Stop the ticker to release associated
+// resources.
+func NewJitterTicker(d, jitter time.Duration) *JitterTicker {
+	if d <= 0 {
+		panic("timer: non-positive interval for NewJitterTicker")
+	}
+	c := make(chan time.Time, 1)
+	jt := &JitterTicker{
+		C:      c,
+		d:      d,
+		jitter: min(max(jitter, 0), d/2),
+	}
+	jt.t = &Timer{
+		s: pickShard(),
+		f: func(now *time.Time) {
+			// Don't block.
+			select {
+			case c <- *now:
+			default:
+			}
+		},
+		reset: func() bool { return false },
+		next:  jt.next,
+	}
+	jt.start = jt.t.s.clock.Now()
+	addTimerAt(jt.t, jt.next(jt.start))
+	return jt
+}
+
+// Stop turns off the ticker. After Stop, no more ticks will be sent.
+// Stop does not close the channel.
+func (jt *JitterTicker) Stop() {
+	if jt.t == nil {
+		panic("timer: Stop called on uninitialized JitterTicker")
+	}
+	delTimer(jt.t)
+}
+
+// Return the time of the next tick after now.
+// This is called by the timer routine with the shard lock held.
+func (jt *JitterTicker) next(now time.Time) time.Time {
+	// A tick with a negative jitter fires before its anchor,
+	// hence the next tick is at least the following one.
+	jt.n = max(jt.n+1, now.Sub(jt.start)/jt.d+1)
+	anchor := jt.start.Add(jt.n * jt.d)
+	return anchor.Add(jitterDuration(jt.jitter, jt.jitter) - jt.jitter)
+}
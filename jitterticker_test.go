@@ -0,0 +1,57 @@
+package timer
+
+import (
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJitterTicker(t *testing.T) {
+	// Keep the scheduler busy.
+	var stop atomic.Bool
+	defer stop.Store(true)
+	for i := 0; i < runtime.NumCPU(); i++ {
+		go func() {
+			for !stop.Load() {
+			}
+		}()
+	}
+
+	const (
+		d      = 100 * time.Millisecond
+		jitter = 10 * time.Millisecond
+		tol    = 50 * time.Millisecond
+	)
+	jt := NewJitterTicker(d, jitter)
+	defer jt.Stop()
+
+	var last time.Duration
+	for i := 0; i < 10; i++ {
+		since := (<-jt.C).Sub(jt.start)
+		// Unique as long as 2*jitter + tol < d.
+		n := (since + jitter) / d
+		if n <= last {
+			t.Fatalf("jitter ticker: tick %d repeated", n)
+		}
+		if off := since - n*d; off < -jitter || off > jitter+tol {
+			t.Errorf("jitter ticker: tick %d is %v off its anchor", n, off)
+		}
+		last = n
+	}
+	if last < 10 {
+		t.Errorf("jitter ticker: received tick %d as 10th tick", last)
+	}
+
+	jt.Stop()
+	time.Sleep(2 * d)
+	select {
+	case <-jt.C:
+		select {
+		case <-jt.C:
+			t.Errorf("jitter ticker: ticks after stop")
+		case <-time.After(2 * d):
+		}
+	default:
+	}
+}
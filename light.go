@@ -0,0 +1,227 @@
+package timer
+
+import (
+	"container/heap"
+	"time"
+)
+
+// A LightFire is delivered by a LightQueue each time one of its timers fires.
+type LightFire struct {
+	ID   uint64
+	Time time.Time
+}
+
+// A LightQueue fans in the fires of many LightTimers into a single channel,
+// which callers multiplex by the id of the timer. The fires are delivered
+// on C in the order of their fire times by a single routine.
+//
+// The queue keeps its timers in its own heap, which is woken by a single
+// Timer at the deadline of the timer which expires next. Hence the queue
+// counts as one timer for ActiveTimers, and ShiftAll and SetTimeScale do
+// not move the deadlines of its scheduled timers.
+//
+// A LightQueue must be created with NewLightQueue.
+type LightQueue struct {
+	C <-chan LightFire // The channel on which the fires are delivered.
+
+	f *fanIn[LightFire]
+	t *Timer // Wakes the queue. Its shard lock guards the fields below.
+
+	base    time.Time // Origin of the deadlines of the timers.
+	heap    lightHeap // Scheduled timers ordered by their deadline.
+	stopped bool
+}
+
+// A LightTimer is a timer without its own channel, intended for millions of
+// mostly dormant timers. Its fires are delivered by its LightQueue.
+//
+// A Timer allocates a channel with a buffer of one time.Time and the state
+// of its closures, several hundred bytes on 64-bit platforms. A LightTimer
+// only holds its deadline, its id and its position in the heap of its queue,
+// 32 bytes plus its heap slot. Run the DormantTimers benchmarks to measure
+// the bytes per timer.
+type LightTimer struct {
+	q    *LightQueue
+	when int64 // Deadline in nanoseconds since q.base.
+	id   uint64
+	i    int // Index in the heap of the queue, -1 if not scheduled.
+}
+
+// NewLightQueue creates a new LightQueue and starts its delivery routine.
+// Stop must be called to release the routine.
+func NewLightQueue() *LightQueue {
+	f := newFanIn[LightFire]()
+	q := &LightQueue{
+		C: f.c,
+		f: f,
+	}
+	q.t = &Timer{
+		s:     pickShard(),
+		reset: func() bool { return false },
+	}
+	q.t.f = func(now *time.Time) {
+		q.fireLocked(*now)
+	}
+	q.t.next = q.nextLocked
+	q.base = q.t.s.clock.Now()
+	return q
+}
+
+// Add creates a new LightTimer in the queue that fires after at least
+// duration d. The fire is delivered as a LightFire with the given id on q.C.
+// Ids are not required to be unique.
+// The queue references the timer only while it is scheduled.
+func (q *LightQueue) Add(id uint64, d time.Duration) *LightTimer {
+	lt := &LightTimer{q: q, id: id, i: -1}
+
+	s := q.t.s
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if q.stopped {
+		panic("timer: Add called on stopped LightQueue")
+	}
+	q.scheduleLocked(lt, s.clock.Now().Add(scaled(d)))
+	return lt
+}
+
+// Stop stops all timers of the queue, drops the undelivered fires
+// and closes q.C. Stop must not be called concurrently with Add.
+// Calling Stop more than once is a no-op.
+func (q *LightQueue) Stop() {
+	s := q.t.s
+	s.mutex.Lock()
+	if q.stopped {
+		s.mutex.Unlock()
+		return
+	}
+	q.stopped = true
+	for _, lt := range q.heap {
+		lt.i = -1
+	}
+	q.heap = nil
+	s.delTimerLocked(q.t)
+	s.mutex.Unlock()
+
+	q.f.close()
+}
+
+// Schedule the timer lt at when and wake the queue in time.
+// Must be called with the shard lock held.
+func (q *LightQueue) scheduleLocked(lt *LightTimer, when time.Time) {
+	lt.when = int64(when.Sub(q.base))
+	if lt.i >= 0 {
+		heap.Fix(&q.heap, lt.i)
+	} else {
+		heap.Push(&q.heap, lt)
+	}
+
+	if q.heap[0] == lt {
+		s := q.t.s
+		if !s.activeTimerLocked(q.t) || when.Before(q.t.when) {
+			s.resetTimerLocked(q.t, when, 0)
+		}
+	}
+}
+
+// Queue the fires of the expired timers.
+// This is called by the timer routine with the shard lock held.
+func (q *LightQueue) fireLocked(now time.Time) {
+	n := int64(now.Sub(q.base))
+	for len(q.heap) > 0 && q.heap[0].when <= n {
+		lt := heap.Pop(&q.heap).(*LightTimer)
+		q.f.push(fanInEntry[LightFire]{
+			key:  lt,
+			when: now,
+			e:    LightFire{ID: lt.id, Time: now},
+		})
+	}
+}
+
+// Return the deadline of the timer which expires next, or the zero time
+// to remove the waking Timer if no timer is scheduled.
+// This is called by the timer routine with the shard lock held.
+func (q *LightQueue) nextLocked(time.Time) time.Time {
+	if len(q.heap) == 0 {
+		return time.Time{}
+	}
+	return q.base.Add(time.Duration(q.heap[0].when))
+}
+
+// ID returns the id the timer was added with.
+func (lt *LightTimer) ID() uint64 {
+	return lt.id
+}
+
+// Reset changes the timer to expire after duration d and removes its
+// undelivered fire from the queue. It returns true if the timer had been
+// active, false if the timer had expired or been stopped.
+// A timer of a stopped queue is not scheduled again.
+func (lt *LightTimer) Reset(d time.Duration) bool {
+	q := lt.q
+	s := q.t.s
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	active := lt.i >= 0
+	q.f.remove(lt)
+	if !q.stopped {
+		q.scheduleLocked(lt, s.clock.Now().Add(scaled(d)))
+	}
+	return active
+}
+
+// Stop prevents the timer from firing and removes its undelivered fire from
+// the queue. It returns true if the call stops the timer, false if the timer
+// has already expired or been stopped.
+func (lt *LightTimer) Stop() bool {
+	q := lt.q
+	s := q.t.s
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	q.f.remove(lt)
+	if lt.i < 0 {
+		return false
+	}
+	heap.Remove(&q.heap, lt.i)
+	return true
+}
+
+// Active reports whether the timer is scheduled.
+func (lt *LightTimer) Active() bool {
+	s := lt.q.t.s
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return lt.i >= 0
+}
+
+// lightHeap implements heap.Interface for the timers of a LightQueue.
+type lightHeap []*LightTimer
+
+func (h lightHeap) Len() int { return len(h) }
+
+func (h lightHeap) Less(i, j int) bool { return h[i].when < h[j].when }
+
+func (h lightHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].i = i
+	h[j].i = j
+}
+
+func (h *lightHeap) Push(x interface{}) {
+	lt := x.(*LightTimer)
+	lt.i = len(*h)
+	*h = append(*h, lt)
+}
+
+func (h *lightHeap) Pop() interface{} {
+	old := *h
+	n := len(old) - 1
+	lt := old[n]
+	old[n] = nil
+	lt.i = -1
+	*h = old[:n]
+	return lt
+}
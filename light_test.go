@@ -0,0 +1,143 @@
+package timer
+
+import (
+	"math/rand"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestLightQueue(t *testing.T) {
+	q := NewLightQueue()
+	defer q.Stop()
+
+	q.Add(3, 30*time.Millisecond)
+	q.Add(1, 10*time.Millisecond)
+	lt := q.Add(2, 20*time.Millisecond)
+	stopped := q.Add(4, 5*time.Millisecond)
+	if !stopped.Stop() || stopped.Active() {
+		t.Errorf("light queue: timer was not stopped")
+	}
+
+	for i, want := range []uint64{1, 2, 3} {
+		select {
+		case f := <-q.C:
+			if f.ID != want {
+				t.Fatalf("light queue: fire %d has id %d, expected %d", i, f.ID, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("light queue: fire %d not delivered", i)
+		}
+	}
+
+	// A reset removes the undelivered fire.
+	lt.Reset(0)
+	time.Sleep(10 * time.Millisecond)
+	if lt.Reset(20 * time.Millisecond) {
+		t.Errorf("light queue: expired timer was active")
+	}
+	select {
+	case f := <-q.C:
+		if f.ID != lt.ID() || time.Since(f.Time) > 10*time.Millisecond {
+			t.Errorf("light queue: stale fire delivered")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("light queue: reset timer not delivered")
+	}
+}
+
+func TestLightQueueStop(t *testing.T) {
+	q := NewLightQueue()
+	lt := q.Add(1, time.Hour)
+	q.Stop()
+	if lt.Active() {
+		t.Errorf("light queue: timer active after stop")
+	}
+	if _, ok := <-q.C; ok {
+		t.Errorf("light queue: channel not closed")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("light queue: add after stop did not panic")
+		}
+	}()
+	q.Add(2, time.Hour)
+}
+
+func TestLightQueueMany(t *testing.T) {
+	q := NewLightQueue()
+	defer q.Stop()
+
+	const n = 1000
+	timers := make([]*LightTimer, n)
+	for i := range timers {
+		timers[i] = q.Add(uint64(i), time.Duration(rand.Intn(n))*time.Microsecond)
+	}
+	// Stopped and reset timers are removed or moved within the heap.
+	for i := 0; i < n; i += 10 {
+		timers[i].Stop()
+		timers[i+1].Reset(time.Duration(rand.Intn(n)) * time.Microsecond)
+	}
+
+	seen := make([]bool, n)
+	var last time.Time
+	for i := 0; i < n-n/10; i++ {
+		select {
+		case f := <-q.C:
+			if seen[f.ID] || f.ID%10 == 0 {
+				t.Fatalf("light queue: unexpected fire of timer %d", f.ID)
+			}
+			if f.Time.Before(last) {
+				t.Fatalf("light queue: fire %d delivered out of order", i)
+			}
+			seen[f.ID] = true
+			last = f.Time
+		case <-time.After(time.Second):
+			t.Fatalf("light queue: only %d fires delivered", i)
+		}
+	}
+
+	// The queue does not reference fired timers.
+	s := q.t.s
+	s.mutex.Lock()
+	l, active := len(q.heap), s.activeTimerLocked(q.t)
+	s.mutex.Unlock()
+	if l != 0 || active {
+		t.Errorf("light queue: %d timers left, waking timer active: %v", l, active)
+	}
+}
+
+// Measure the memory of 1M dormant timers.
+func benchmarkDormant(b *testing.B, add func(i int) func() bool) {
+	const n = 1000000
+	stops := make([]func() bool, n)
+	var before, after runtime.MemStats
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		for j := range stops {
+			stops[j] = add(j)
+		}
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		for j := range stops {
+			stops[j]()
+		}
+	}
+	b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/n, "B/timer")
+}
+
+func BenchmarkDormantTimers(b *testing.B) {
+	benchmarkDormant(b, func(int) func() bool {
+		return NewTimer(time.Hour).Stop
+	})
+}
+
+func BenchmarkDormantLightTimers(b *testing.B) {
+	q := NewLightQueue()
+	defer q.Stop()
+	benchmarkDormant(b, func(i int) func() bool {
+		return q.Add(uint64(i), time.Hour).Stop
+	})
+}
@@ -0,0 +1,144 @@
+package timer
+
+import (
+	"time"
+)
+
+// An Option configures a Timer created with NewTimerWithOptions.
+type Option func(o *options)
+
+type options struct {
+	unbuffered   bool
+	closeOnStop  bool
+	doneChan     bool
+	signal       bool
+	tag          interface{}
+	callbackPool *CallbackPool
+	onFire       func(time.Time)
+	clock        Clock
+	minInterval  time.Duration
+	truncate     time.Duration
+}
+
+// Return the shard for a new timer.
+func (o *options) shard() *shard {
+	if o.clock != nil {
+		return clockShard(o.clock)
+	}
+	return pickShard()
+}
+
+// WithUnbuffered creates the Timer with an unbuffered channel C.
+// The fire is only delivered if a receiver is waiting on C at the moment
+// the timer expires, otherwise the value is dropped. The timer routine
+// never blocks on a missing receiver.
+//
+// Because no value is ever pending in C, Stop and Reset have nothing to drain.
+// A Stop which returns false means the timer either delivered the value to
+// a waiting receiver or dropped it.
+func WithUnbuffered() Option {
+	return func(o *options) {
+		o.unbuffered = true
+	}
+}
+
+// WithCloseOnStop closes the channel C when the Timer is stopped.
+// Receivers blocked on C wake up immediately with the zero time and
+// a two-value receive reports ok == false, similar to a cancelled context.
+// A fire is never sent on a closed channel.
+//
+// The channel can not be reopened. A Reset after Stop reschedules the
+// Timer, but C stays closed and the fire is dropped.
+func WithCloseOnStop() Option {
+	return func(o *options) {
+		o.closeOnStop = true
+	}
+}
+
+// WithSignalChan creates the Timer with the channel Sig instead of C,
+// on which an empty struct is sent when the Timer fires. It suits timers
+// whose fire time does not matter, like a plain "timeout happened".
+// Sig and C are mutually exclusive: C is nil if the option is given,
+// Sig is nil otherwise.
+//
+// Stop, Reset and Drain clear Sig like C and the other options apply to Sig
+// as well. Methods which deliver or receive on C, like StopWithReason,
+// ResetChan, TryReceive or WaitContext, do not apply to Sig.
+func WithSignalChan() Option {
+	return func(o *options) {
+		o.signal = true
+	}
+}
+
+// WithDoneChan creates a channel which is returned by Done and closed when
+// the Timer is stopped by Stop or any of its variants, whether the timer was
+// active or not. A fire does not close it, hence a closed channel means
+// stopped, not fired. It signals a Stop to goroutines other than the
+// receiver of C, for example to tear down related work.
+//
+// A Reset after Stop creates a fresh channel, which is returned by
+// subsequent calls to Done. Receivers of the old channel are not affected.
+func WithDoneChan() Option {
+	return func(o *options) {
+		o.doneChan = true
+	}
+}
+
+// WithOnFire calls f each time the Timer fires, right after the value was
+// delivered to C. f is called with the fire time by the shared timer
+// routine while the timer heap is locked, hence it must be fast, must not
+// block and must not call methods of any Timer. A panic in f is recovered
+// and drops the Timer, see SetRunnerPanicHandler.
+// WithCallbackPool lifts these restrictions.
+func WithOnFire(f func(t time.Time)) Option {
+	return func(o *options) {
+		o.onFire = f
+	}
+}
+
+// WithMinInterval lets the Timer fire at most once per interval d.
+// A Reset which would fire the timer sooner than d after its last fire is
+// scheduled at last fire + d instead, hence a burst of resets within the
+// interval results in a single fire at the end of the interval.
+// This applies to all ways of scheduling the timer, but not to the
+// periods of ResetPeriodic.
+func WithMinInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.minInterval = d
+	}
+}
+
+// WithTruncate truncates the time sent on C to a multiple of d since the
+// zero time, like time.Time.Truncate, which also strips the monotonic clock
+// reading. It reduces the noise of fire times which are logged or compared.
+// Only the value sent on C is truncated, the Timer still fires at its
+// deadline and WithOnFire receives the exact fire time.
+// A d less than or equal to zero disables the truncation, which is
+// the default.
+func WithTruncate(d time.Duration) Option {
+	return func(o *options) {
+		o.truncate = d
+	}
+}
+
+// WithClock drives the Timer by the given clock instead of the system clock.
+func WithClock(clk Clock) Option {
+	return func(o *options) {
+		o.clock = clk
+	}
+}
+
+// NewTimerWithOptions creates a new Timer configured by the given options
+// that will send the current time on its channel after at least duration d.
+func NewTimerWithOptions(d time.Duration, opts ...Option) *Timer {
+	t := newStoppedTimer(newOptions(opts))
+	addTimer(t, d)
+	return t
+}
+
+func newOptions(opts []Option) (o options) {
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return
+}
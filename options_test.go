@@ -0,0 +1,295 @@
+package timer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnbuffered(t *testing.T) {
+	timer := NewTimerWithOptions(100*time.Millisecond, WithUnbuffered())
+	if cap(timer.C) != 0 {
+		t.Errorf("unbuffered: channel should be unbuffered")
+	}
+
+	// No receiver is waiting, hence the value is dropped.
+	time.Sleep(200 * time.Millisecond)
+	select {
+	case <-timer.C:
+		t.Errorf("unbuffered: value should have been dropped")
+	default:
+	}
+
+	// A waiting receiver gets the value.
+	start := time.Now()
+	if timer.Reset(100 * time.Millisecond) {
+		t.Errorf("unbuffered: was active is true")
+	}
+	<-timer.C
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > 200*time.Millisecond {
+		t.Errorf("unbuffered: took %v", elapsed)
+	}
+}
+
+func TestCloseOnStop(t *testing.T) {
+	timer := NewTimerWithOptions(time.Hour, WithCloseOnStop())
+
+	done := make(chan struct{})
+	go func() {
+		_, ok := <-timer.C
+		if ok {
+			t.Errorf("close on stop: channel is not closed")
+		}
+		close(done)
+	}()
+
+	if !timer.Stop() {
+		t.Errorf("close on stop: was active is false")
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("close on stop: receiver was not woken")
+	}
+
+	// Stop again must not close twice and a reset must not send on the closed channel.
+	timer.Stop()
+	timer.Reset(0)
+	time.Sleep(50 * time.Millisecond)
+	if timer.Drain() {
+		t.Errorf("close on stop: drained a closed channel")
+	}
+}
+
+func TestCloseOnStopFire(t *testing.T) {
+	timer := NewTimerWithOptions(50*time.Millisecond, WithCloseOnStop())
+	if _, ok := <-timer.C; !ok {
+		t.Errorf("close on stop: channel closed on fire")
+	}
+}
+
+func TestOnFire(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	var fires []time.Time
+	timer := NewTimerWithOptions(time.Second, WithClock(clk), WithOnFire(func(t time.Time) {
+		fires = append(fires, t)
+	}))
+
+	clk.Advance(time.Second)
+	if len(fires) != 1 || !fires[0].Equal(time.Unix(1, 0)) {
+		t.Fatalf("on fire: invalid fires: %v", fires)
+	}
+	if len(timer.C) != 1 {
+		t.Errorf("on fire: value was not delivered")
+	}
+
+	// Called even if the channel is full.
+	timer.ResetPeriodic(time.Second)
+	clk.Advance(time.Second)
+	if len(timer.C) != 1 {
+		t.Errorf("on fire: value was not delivered")
+	}
+	clk.Advance(2 * time.Second)
+	if len(fires) != 4 {
+		t.Errorf("on fire: invalid fire count: %v", len(fires))
+	}
+
+	timer.Stop()
+	clk.Advance(time.Hour)
+	if len(fires) != 4 {
+		t.Errorf("on fire: stopped timer fired")
+	}
+}
+
+func TestMinInterval(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	var fires []time.Time
+	timer := NewTimerWithOptions(0, WithClock(clk), WithMinInterval(time.Second), WithOnFire(func(t time.Time) {
+		fires = append(fires, t)
+	}))
+	if len(fires) != 1 {
+		t.Fatalf("min interval: first fire was delayed")
+	}
+
+	// A burst of resets within the interval results in a single fire
+	// at the end of the interval.
+	for i := 0; i < 10; i++ {
+		timer.Reset(0)
+		clk.Advance(50 * time.Millisecond)
+	}
+	if len(fires) != 1 {
+		t.Fatalf("min interval: fired within the interval: %v", fires)
+	}
+	clk.Advance(500 * time.Millisecond)
+	if len(fires) != 2 || !fires[1].Equal(time.Unix(1, 0)) {
+		t.Fatalf("min interval: invalid fires: %v", fires)
+	}
+
+	// Resets later than the floor are not delayed.
+	timer.Reset(2 * time.Second)
+	if !timer.Deadline().Equal(time.Unix(3, 0)) {
+		t.Errorf("min interval: invalid deadline %v", timer.Deadline())
+	}
+}
+
+func TestRunnerPanicHandler(t *testing.T) {
+	recovered := make(chan interface{}, 1)
+	SetRunnerPanicHandler(func(r interface{}) {
+		recovered <- r
+	})
+	defer SetRunnerPanicHandler(nil)
+
+	bad := NewTimerWithOptions(0, WithOnFire(func(time.Time) {
+		panic("boom")
+	}))
+	select {
+	case r := <-recovered:
+		if r != "boom" {
+			t.Errorf("runner panic: invalid recovered value %v", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("runner panic: handler was not called")
+	}
+	if bad.Active() {
+		t.Errorf("runner panic: offending timer is still active")
+	}
+
+	// Unrelated timers still fire afterwards.
+	good := NewTimer(10 * time.Millisecond)
+	select {
+	case <-good.C:
+	case <-time.After(time.Second):
+		t.Fatalf("runner panic: timer did not fire after the panic")
+	}
+
+	// A periodic timer is dropped as well.
+	clk := NewFakeClock(time.Unix(0, 0))
+	var fires int
+	periodic := NewTimerWithOptions(time.Second, WithClock(clk), WithOnFire(func(time.Time) {
+		fires++
+		panic("boom")
+	}))
+	periodic.ResetPeriodic(time.Second)
+	clk.Advance(3 * time.Second)
+	<-recovered
+	if fires != 1 || periodic.Active() {
+		t.Errorf("runner panic: periodic timer was not dropped")
+	}
+}
+
+func TestDoneChan(t *testing.T) {
+	timer := NewTimerWithOptions(10*time.Millisecond, WithDoneChan())
+	done := timer.Done()
+
+	// A fire does not close the channel.
+	<-timer.C
+	if isClosed(done) {
+		t.Fatalf("done chan: closed by a fire")
+	}
+
+	timer.Reset(time.Hour)
+	if timer.Done() != done {
+		t.Errorf("done chan: replaced without a stop")
+	}
+	go timer.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("done chan: not closed by stop")
+	}
+	timer.Stop()
+
+	// A reset after the stop creates a fresh channel.
+	timer.Reset(time.Hour)
+	if d := timer.Done(); d == done || isClosed(d) {
+		t.Errorf("done chan: no fresh channel after reset")
+	}
+	timer.StopAndDrain()
+	if !isClosed(timer.Done()) {
+		t.Errorf("done chan: not closed by StopAndDrain")
+	}
+
+	// Without the option, Done blocks forever.
+	if NewStoppedTimer().Done() != nil {
+		t.Errorf("done chan: timer without option has a channel")
+	}
+
+	// The option works together with WithCloseOnStop.
+	timer = NewTimerWithOptions(time.Hour, WithDoneChan(), WithCloseOnStop())
+	timer.Stop()
+	if _, ok := <-timer.C; ok || !isClosed(timer.Done()) {
+		t.Errorf("done chan: channels not closed with WithCloseOnStop")
+	}
+}
+
+func TestSignalChan(t *testing.T) {
+	timer := NewTimerWithOptions(10*time.Millisecond, WithSignalChan())
+	if timer.C != nil || timer.Sig == nil {
+		t.Fatalf("signal chan: invalid channels")
+	}
+	select {
+	case <-timer.Sig:
+	case <-time.After(time.Second):
+		t.Fatalf("signal chan: timer did not fire")
+	}
+
+	// Reset and Stop drain Sig.
+	timer.Reset(0)
+	time.Sleep(10 * time.Millisecond)
+	if timer.Reset(time.Hour) || len(timer.Sig) != 0 {
+		t.Errorf("signal chan: reset did not drain")
+	}
+	if !timer.Stop() {
+		t.Errorf("signal chan: stop returned false")
+	}
+	timer.Reset(0)
+	time.Sleep(10 * time.Millisecond)
+	if timer.StopAndDrain() || len(timer.Sig) != 0 {
+		t.Errorf("signal chan: stop and drain did not drain")
+	}
+
+	// A pending signal counts like a pending value in C.
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer = NewTimerWithOptions(time.Second, WithSignalChan(), WithClock(clk))
+	clk.Advance(time.Second)
+	if timer.IsStopped() || timer.String() != "Timer(fired, undrained)" {
+		t.Errorf("signal chan: pending signal not reported by %v", timer)
+	}
+	<-timer.Sig
+	if !timer.IsStopped() {
+		t.Errorf("signal chan: drained timer not stopped")
+	}
+
+	timer = NewTimerWithOptions(time.Hour, WithSignalChan(), WithCloseOnStop())
+	timer.Stop()
+	if _, ok := <-timer.Sig; ok {
+		t.Errorf("signal chan: not closed on stop")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	var onFire time.Time
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithOptions(1234567*time.Microsecond, WithClock(clk),
+		WithTruncate(time.Second/10), WithOnFire(func(t time.Time) { onFire = t }))
+
+	clk.Advance(time.Hour)
+	if v := <-timer.C; !v.Equal(time.Unix(1, 2e8)) {
+		t.Errorf("truncate: invalid time value %v", v)
+	}
+	if !onFire.Equal(time.Unix(1, 234567e3)) {
+		t.Errorf("truncate: invalid on fire time %v", onFire)
+	}
+
+	// No truncation by default.
+	timer = NewTimerWithOptions(1234567*time.Microsecond, WithClock(clk))
+	clk.Advance(time.Hour)
+	if v := <-timer.C; !v.Equal(time.Unix(3601, 234567e3)) {
+		t.Errorf("truncate: time value %v should not be truncated", v)
+	}
+
+	// A system clock timer delivers a truncated wall clock time.
+	timer = NewTimerWithOptions(10*time.Millisecond, WithTruncate(time.Millisecond))
+	if v := <-timer.C; v.Nanosecond()%int(time.Millisecond) != 0 {
+		t.Errorf("truncate: time value %v not truncated to milliseconds", v)
+	}
+}
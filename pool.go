@@ -0,0 +1,43 @@
+package timer
+
+import (
+	"sync"
+	"time"
+)
+
+var timerPool = sync.Pool{
+	New: func() interface{} {
+		return NewStoppedTimer()
+	},
+}
+
+// GetTimer returns a Timer from the pool that will send the current time
+// on its channel after at least duration d. It behaves like NewTimer, but
+// recycles timers returned with PutTimer to avoid allocations.
+func GetTimer(d time.Duration) *Timer {
+	if synctestMode.Load() {
+		// Pooled timers are not bound to the caller's bubble.
+		return NewTimer(d)
+	}
+	t := timerPool.Get().(*Timer)
+	setTimerPooled(t, false)
+	if resetTimer(t, d, 0) {
+		panic("timer: GetTimer returned an active Timer: Timer used after PutTimer")
+	}
+	return t
+}
+
+// PutTimer stops the Timer, drains its channel and returns it to the pool.
+// The Timer must have been created by GetTimer, NewTimer or NewStoppedTimer
+// and must not be used after this call.
+func PutTimer(t *Timer) {
+	if t.f == nil {
+		panic("timer: PutTimer called on uninitialized Timer")
+	}
+	checkStrict(t, "PutTimer")
+	delTimerAndReset(t)
+	setTimerPooled(t, true)
+	if !t.s.private {
+		timerPool.Put(t)
+	}
+}
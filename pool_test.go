@@ -0,0 +1,72 @@
+package timer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	start := time.Now()
+	timer := GetTimer(100 * time.Millisecond)
+	<-timer.C
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > 200*time.Millisecond {
+		t.Errorf("pool: took %v", elapsed)
+	}
+	PutTimer(timer)
+
+	// A recycled timer is stopped and drained.
+	timer = GetTimer(time.Hour)
+	timer.Reset(0)
+	time.Sleep(50 * time.Millisecond)
+	PutTimer(timer)
+	if timer.Active() || len(timer.C) != 0 {
+		t.Errorf("pool: put timer is not stopped")
+	}
+}
+
+func BenchmarkNewTimerHotLoop(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		timer := NewTimer(time.Hour)
+		timer.Stop()
+	}
+}
+
+func BenchmarkGetTimerHotLoop(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		timer := GetTimer(time.Hour)
+		PutTimer(timer)
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	SetStrictMode(true)
+	defer SetStrictMode(false)
+
+	expectPanic := func(name string, f func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("strict mode: no panic for %v", name)
+			}
+		}()
+		f()
+	}
+
+	timer := GetTimer(time.Hour)
+	PutTimer(timer)
+	expectPanic("Reset after PutTimer", func() { timer.Reset(time.Second) })
+	expectPanic("second PutTimer", func() { PutTimer(timer) })
+
+	timer = GetTimer(time.Hour)
+	timer.Reset(time.Second)
+	timer.Stop()
+	expectPanic("WaitContext on stopped timer", func() { timer.WaitContext(context.Background()) })
+
+	// Misuse is not detected if strict mode is disabled.
+	SetStrictMode(false)
+	PutTimer(timer)
+	timer.Stop()
+}
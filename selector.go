@@ -0,0 +1,108 @@
+package timer
+
+import (
+	"sync"
+	"time"
+)
+
+// A Selection is delivered by a Selector each time one of its timers fires.
+type Selection struct {
+	Name string
+	Time time.Time
+}
+
+// A Selector multiplexes the fires of named timers into a single channel,
+// like per-peer heartbeat deadlines. The fires are delivered on C in the
+// order of their fire times by a single routine.
+//
+// A Selector must be created with NewSelector.
+type Selector struct {
+	C <-chan Selection // The channel on which the fires are delivered.
+
+	f *fanIn[Selection]
+
+	mutex   sync.Mutex
+	timers  map[string]*Timer
+	stopped bool
+}
+
+// NewSelector creates a new Selector and starts its delivery routine.
+// Stop must be called to release the routine.
+func NewSelector() *Selector {
+	f := newFanIn[Selection]()
+	return &Selector{
+		C:      f.c,
+		f:      f,
+		timers: make(map[string]*Timer),
+	}
+}
+
+// Add schedules the timer name to fire after at least duration d.
+// If the timer already exists, it is reset like by Reset.
+func (s *Selector) Add(name string, d time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stopped {
+		panic("timer: Add called on stopped Selector")
+	}
+	if t, ok := s.timers[name]; ok {
+		resetTimer(t, d, 0)
+		return
+	}
+	t := s.f.newTimer(func(_ *Timer, now time.Time) Selection {
+		return Selection{Name: name, Time: now}
+	})
+	s.timers[name] = t
+	addTimer(t, d)
+}
+
+// Reset changes the timer name to fire after duration d and removes its
+// undelivered fire. It returns true if the timer had been active, false if
+// the timer had expired, been stopped or does not exist.
+// Reset does not add unknown timers.
+func (s *Selector) Reset(name string, d time.Duration) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t, ok := s.timers[name]
+	if !ok {
+		return false
+	}
+	return resetTimer(t, d, 0)
+}
+
+// Remove stops and removes the timer name and its undelivered fire.
+// It returns true if the call stops the timer, false if the timer had
+// expired, been stopped or does not exist.
+func (s *Selector) Remove(name string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t, ok := s.timers[name]
+	if !ok {
+		return false
+	}
+	delete(s.timers, name)
+	return stopTimer(t)
+}
+
+// Stop stops all timers of the selector, drops the undelivered fires
+// and closes s.C. Calling Stop more than once is a no-op.
+func (s *Selector) Stop() {
+	s.mutex.Lock()
+	if s.stopped {
+		s.mutex.Unlock()
+		return
+	}
+	s.stopped = true
+	ts := make([]*Timer, 0, len(s.timers))
+	for _, t := range s.timers {
+		ts = append(ts, t)
+	}
+	s.timers = nil
+	s.mutex.Unlock()
+
+	stopTimers(ts)
+	s.f.close()
+}
@@ -0,0 +1,73 @@
+package timer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSelector(t *testing.T) {
+	s := NewSelector()
+	defer s.Stop()
+
+	s.Add("c", 30*time.Millisecond)
+	s.Add("a", 10*time.Millisecond)
+	s.Add("b", 20*time.Millisecond)
+
+	for _, want := range []string{"a", "b", "c"} {
+		select {
+		case sel := <-s.C:
+			if sel.Name != want {
+				t.Fatalf("selector: got %v, want %v", sel.Name, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("selector: %v did not fire", want)
+		}
+	}
+}
+
+func TestSelectorResetRemove(t *testing.T) {
+	s := NewSelector()
+	defer s.Stop()
+
+	s.Add("a", 0)
+	s.Add("b", time.Hour)
+	time.Sleep(50 * time.Millisecond)
+
+	// Reset removes the undelivered fire.
+	if s.Reset("a", time.Hour) {
+		t.Errorf("selector: was active is true")
+	}
+	if !s.Reset("b", 10*time.Millisecond) {
+		t.Errorf("selector: was active is false")
+	}
+	if s.Reset("unknown", 0) {
+		t.Errorf("selector: unknown timer was reset")
+	}
+	select {
+	case sel := <-s.C:
+		if sel.Name != "b" {
+			t.Errorf("selector: got %v, want b", sel.Name)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("selector: b did not fire")
+	}
+
+	if !s.Remove("a") {
+		t.Errorf("selector: remove of active timer returned false")
+	}
+	if s.Remove("a") {
+		t.Errorf("selector: removed timer removed again")
+	}
+}
+
+func TestSelectorStop(t *testing.T) {
+	s := NewSelector()
+	s.Add("a", 0)
+	time.Sleep(50 * time.Millisecond)
+	s.Stop()
+	s.Stop()
+
+	if _, ok := <-s.C; ok {
+		t.Errorf("selector: channel not closed after Stop")
+	}
+}
@@ -0,0 +1,48 @@
+package timer
+
+import (
+	"context"
+	"time"
+)
+
+// Sleep pauses the current goroutine for at least the duration d using a
+// pooled Timer. Unlike time.Sleep, it follows SetTimeScale and synctest
+// mode. A duration of zero or less returns immediately.
+func Sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	t := GetTimer(d)
+	<-t.C
+	PutTimer(t)
+}
+
+// SleepContext pauses the current goroutine like Sleep until the duration d
+// elapsed or ctx is done. It returns ctx.Err() if ctx is done first,
+// nil otherwise. The timer is released in either case.
+func SleepContext(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if d <= 0 {
+		return nil
+	}
+	t := GetTimer(d)
+	defer PutTimer(t)
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SleepWithClock pauses the current goroutine until the duration d elapsed
+// on the clock clk. A duration of zero or less returns immediately.
+func SleepWithClock(clk Clock, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	<-NewTimerWithClock(clk, d).C
+}
@@ -0,0 +1,72 @@
+package timer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSleep(t *testing.T) {
+	start := time.Now()
+	Sleep(10 * time.Millisecond)
+	if d := time.Since(start); d < 10*time.Millisecond {
+		t.Errorf("sleep: returned after %v", d)
+	}
+	Sleep(-time.Second)
+}
+
+func TestSleepContext(t *testing.T) {
+	if err := SleepContext(context.Background(), 10*time.Millisecond); err != nil {
+		t.Errorf("sleep context: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	if err := SleepContext(ctx, time.Hour); err != context.Canceled {
+		t.Errorf("sleep context: invalid error %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("sleep context: cancellation returned after %v", d)
+	}
+	if err := SleepContext(ctx, 0); err != context.Canceled {
+		t.Errorf("sleep context: done context returned %v", err)
+	}
+}
+
+func TestSleepWithClock(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	s := clockShard(clk)
+	done := make(chan struct{})
+	go func() {
+		SleepWithClock(clk, time.Second)
+		close(done)
+	}()
+
+	// Wait until the sleeping timer is scheduled.
+	for {
+		s.mutex.Lock()
+		n := s.lenLocked()
+		s.mutex.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	clk.Advance(999 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatalf("sleep with clock: returned early")
+	case <-time.After(10 * time.Millisecond):
+	}
+	clk.Advance(time.Millisecond)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("sleep with clock: did not return")
+	}
+}
@@ -0,0 +1,41 @@
+package timer
+
+import (
+	"time"
+)
+
+// A TimerState describes a scheduled timer in a Snapshot.
+type TimerState struct {
+	Remaining time.Duration // Duration until the timer fires, zero if expired.
+	Period    time.Duration // Period of a periodic timer, zero otherwise.
+	Tag       interface{}   // Tag of the timer, nil if untagged.
+	Prio      int           // Priority set by NewTimerPrio.
+}
+
+// Snapshot returns the state of all scheduled timers in the order of their
+// wake up times. The timers are not modified. All timer heaps are locked at
+// once, hence the snapshot is a consistent cut: no timer fires, stops or
+// resets while it is taken. Paused timers are not included.
+//
+// Together with Replay, Snapshot turns a timer storm of a production
+// process into a reproducible test case. It is intended for diagnostics.
+func Snapshot() []TimerState {
+	return snapshotTimers()
+}
+
+// Replay recreates the timers of a snapshot driven by the clock clk,
+// usually a FakeClock. Each timer fires after its remaining duration
+// and then every period, if periodic, and keeps its tag and priority.
+// The durations of a snapshot are real durations, hence they are not scaled
+// again by SetTimeScale. The timers are returned in the order of the
+// snapshot.
+func Replay(clk Clock, states []TimerState) []*Timer {
+	ts := make([]*Timer, len(states))
+	for i, st := range states {
+		t := newStoppedTimer(options{clock: clk, tag: st.Tag})
+		t.prio = st.Prio
+		resetTimerAt(t, t.s.clock.Now().Add(st.Remaining), st.Period)
+		ts[i] = t
+	}
+	return ts
+}
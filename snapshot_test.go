@@ -0,0 +1,76 @@
+package timer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnapshotReplay(t *testing.T) {
+	type incident string
+	clk := NewFakeClock(time.Unix(0, 0))
+	a := NewTimerWithOptions(3*time.Second, WithClock(clk), WithTag(incident("a")))
+	b := NewTimerWithOptions(time.Hour, WithClock(clk), WithTag(incident("b")))
+	b.ResetPeriodic(2 * time.Second)
+	paused := NewTimerWithOptions(time.Second, WithClock(clk), WithTag(incident("paused")))
+	paused.Pause()
+	defer StopAll([]*Timer{a, b})
+	clk.Advance(time.Second)
+
+	// Keep the states of this test only.
+	var states []TimerState
+	for _, st := range Snapshot() {
+		if _, ok := st.Tag.(incident); ok {
+			states = append(states, st)
+		}
+	}
+	want := []TimerState{
+		{Remaining: time.Second, Period: 2 * time.Second, Tag: incident("b")},
+		{Remaining: 2 * time.Second, Tag: incident("a")},
+	}
+	if len(states) != len(want) {
+		t.Fatalf("snapshot: invalid states %v", states)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Errorf("snapshot: state %d is %v, expected %v", i, states[i], want[i])
+		}
+	}
+
+	// Replay the snapshot on another clock.
+	replay := NewFakeClock(time.Unix(1000, 0))
+	ts := Replay(replay, states)
+	defer StopAll(ts)
+	replay.Advance(time.Second)
+	if len(ts[0].C) != 1 || len(ts[1].C) != 0 {
+		t.Errorf("replay: timers fired out of order")
+	}
+	<-ts[0].C
+	replay.Advance(time.Second)
+	if len(ts[1].C) != 1 {
+		t.Errorf("replay: timer did not fire")
+	}
+	replay.Advance(time.Second)
+	if len(ts[0].C) != 1 {
+		t.Errorf("replay: periodic timer did not fire again")
+	}
+	if ts[1].tag != incident("a") {
+		t.Errorf("replay: tag was not restored")
+	}
+}
+
+func TestReplayTimeScale(t *testing.T) {
+	defer SetTimeScale(1)
+	SetTimeScale(2)
+
+	clk := NewFakeClock(time.Unix(0, 0))
+	ts := Replay(clk, []TimerState{{Remaining: 10 * time.Second, Period: 4 * time.Second}})
+	defer StopAll(ts)
+	if r := ts[0].Remaining(); r != 10*time.Second {
+		t.Errorf("replay: remaining duration %v was scaled", r)
+	}
+	clk.Advance(10 * time.Second)
+	<-ts[0].C
+	if r := ts[0].Remaining(); r != 4*time.Second {
+		t.Errorf("replay: period %v was scaled", r)
+	}
+}
@@ -0,0 +1,43 @@
+package timer
+
+import (
+	"time"
+)
+
+// TimerInterface is the common interface of a *Timer and a *time.Timer
+// wrapped by WrapStd. Code written against it can choose between both
+// implementations, like a library which offers a pluggable timer.
+type TimerInterface interface {
+	// Stop prevents the timer from firing. It returns true if the call
+	// stops the timer, false if the timer has already expired or been stopped.
+	Stop() bool
+
+	// Reset changes the timer to expire after duration d. It returns true
+	// if the timer had been active, false if the timer had expired or been
+	// stopped.
+	Reset(d time.Duration) bool
+}
+
+var _ TimerInterface = (*Timer)(nil)
+
+// WrapStd returns t as a TimerInterface. Stop and Reset are passed to t
+// unchanged, hence they keep the semantics of the standard library, which
+// differ from a *Timer in the Reset of a timer which fired: before Go 1.23,
+// or if the main module declares an older Go version, Reset does not drain
+// t.C and a stale value of the earlier fire is received after the Reset.
+// A *Timer always drains its channel on Reset.
+func WrapStd(t *time.Timer) TimerInterface {
+	return stdTimer{t}
+}
+
+type stdTimer struct {
+	t *time.Timer
+}
+
+func (s stdTimer) Stop() bool {
+	return s.t.Stop()
+}
+
+func (s stdTimer) Reset(d time.Duration) bool {
+	return s.t.Reset(d)
+}
@@ -0,0 +1,39 @@
+package timer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimerInterface(t *testing.T) {
+	impls := map[string]func(d time.Duration) (TimerInterface, <-chan time.Time){
+		"timer": func(d time.Duration) (TimerInterface, <-chan time.Time) {
+			t := NewTimer(d)
+			return t, t.C
+		},
+		"std": func(d time.Duration) (TimerInterface, <-chan time.Time) {
+			t := time.NewTimer(d)
+			return WrapStd(t), t.C
+		},
+	}
+	for name, newTimer := range impls {
+		timer, c := newTimer(time.Hour)
+		if !timer.Reset(10 * time.Millisecond) {
+			t.Errorf("%s: reset of active timer returned false", name)
+		}
+		select {
+		case <-c:
+		case <-time.After(time.Second):
+			t.Fatalf("%s: timer did not fire", name)
+		}
+		if timer.Stop() {
+			t.Errorf("%s: stop of fired timer returned true", name)
+		}
+		if timer.Reset(time.Hour) {
+			t.Errorf("%s: reset of fired timer returned true", name)
+		}
+		if !timer.Stop() {
+			t.Errorf("%s: stop of active timer returned false", name)
+		}
+	}
+}
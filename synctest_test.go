@@ -0,0 +1,61 @@
+//go:build go1.25
+
+package timer
+
+import (
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestSynctestMode(t *testing.T) {
+	SetSynctestMode(true)
+	defer SetSynctestMode(false)
+
+	realStart := time.Now()
+	synctest.Test(t, func(t *testing.T) {
+		start := time.Now()
+		timer := NewTimer(time.Hour)
+		if v := <-timer.C; v.Sub(start) != time.Hour {
+			t.Errorf("synctest: timer fired after %v of fake time", v.Sub(start))
+		}
+
+		// Resets and callbacks follow the fake time as well.
+		timer.Reset(time.Minute)
+		done := make(chan struct{})
+		AfterFunc(2*time.Minute, func() {
+			close(done)
+		})
+		timer.Reset(3 * time.Minute)
+		<-done
+		if timer.Fired() {
+			t.Errorf("synctest: reset timer fired early")
+		}
+		<-timer.C
+		if d := time.Since(start); d != time.Hour+3*time.Minute {
+			t.Errorf("synctest: invalid fake time %v", d)
+		}
+
+		prio := NewTimerPrio(time.Second, 1)
+		<-prio.C
+	})
+	if d := time.Since(realStart); d > time.Second {
+		t.Errorf("synctest: test slept for %v", d)
+	}
+}
+
+func TestSynctestModeSleep(t *testing.T) {
+	// Fill the pool with a timer created outside of synctest mode.
+	PutTimer(GetTimer(time.Hour))
+
+	SetSynctestMode(true)
+	defer SetSynctestMode(false)
+
+	synctest.Test(t, func(t *testing.T) {
+		start := time.Now()
+		Sleep(time.Hour)
+		if d := time.Since(start); d != time.Hour {
+			t.Errorf("synctest: slept for %v of fake time", d)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package timer
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// tags indexes the scheduled timers by their tag.
+	// tagMutex is acquired after the shard mutex.
+	tagMutex sync.Mutex
+	tags     = make(map[interface{}]map[*Timer]struct{})
+
+	// fairTags lets the timer routines take turns between tags.
+	fairTags atomic.Bool
+)
+
+// SetFairTags enables or disables fair firing across tags. If enabled and
+// many timers expire at once, a timer routine fires one timer of each tag
+// in turn, instead of in the order of the heap. One tenant's burst of a
+// thousand timers then does not delay another tenant's single timer.
+// Untagged timers take their turns together like a single tag.
+//
+// Timers still never fire before they expire, only the order of the timers
+// which expired together changes. Priorities set by NewTimerPrio order the
+// timers within a tag only. Timing wheels do not support fair firing.
+// Fair firing is disabled by default.
+func SetFairTags(enabled bool) {
+	fairTags.Store(enabled)
+}
+
+// WithTag associates the Timer with tag like NewTimerTagged.
+func WithTag(tag interface{}) Option {
+	if tag == nil {
+		panic("timer: nil tag for WithTag")
+	}
+	return func(o *options) {
+		o.tag = tag
+	}
+}
+
+// NewTimerTagged creates a new Timer like NewTimer and associates it with
+// tag. All scheduled timers with the same tag can be stopped at once by
+// StopByTag. The tag must be comparable and stays with the timer when it
+// is reset.
+func NewTimerTagged(d time.Duration, tag interface{}) *Timer {
+	if tag == nil {
+		panic("timer: nil tag for NewTimerTagged")
+	}
+	t := NewStoppedTimer()
+	t.tag = tag
+	addTimer(t, d)
+	return t
+}
+
+// StopByTag stops all scheduled timers with the given tag like Stop.
+// It returns the number of timers which were stopped.
+// Timers which already fired are not counted.
+func StopByTag(tag interface{}) (n int) {
+	tagMutex.Lock()
+	set := tags[tag]
+	ts := make([]*Timer, 0, len(set))
+	for t := range set {
+		ts = append(ts, t)
+	}
+	tagMutex.Unlock()
+
+	for _, b := range stopTimers(ts) {
+		if b {
+			n++
+		}
+	}
+	return
+}
+
+// Add the scheduled timer t to the tag index.
+// This is called in a locked context.
+func tagTimer(t *Timer) {
+	tagMutex.Lock()
+	set := tags[t.tag]
+	if set == nil {
+		set = make(map[*Timer]struct{})
+		tags[t.tag] = set
+	}
+	set[t] = struct{}{}
+	tagMutex.Unlock()
+}
+
+// Remove timer t from the tag index, because it is not scheduled anymore.
+// This is called in a locked context.
+func untagTimer(t *Timer) {
+	tagMutex.Lock()
+	set := tags[t.tag]
+	delete(set, t)
+	if len(set) == 0 {
+		delete(tags, t.tag)
+	}
+	tagMutex.Unlock()
+}
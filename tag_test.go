@@ -0,0 +1,88 @@
+package timer
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStopByTag(t *testing.T) {
+	type tenant string
+
+	var timers []*Timer
+	for i := 0; i < 10; i++ {
+		timers = append(timers, NewTimerTagged(time.Hour, tenant("a")))
+	}
+	other := NewTimerTagged(time.Hour, tenant("b"))
+	defer other.Stop()
+	timers[0].Stop()
+
+	if n := StopByTag(tenant("a")); n != 9 {
+		t.Errorf("stop by tag: invalid count %v", n)
+	}
+	for _, timer := range timers {
+		if timer.Active() {
+			t.Errorf("stop by tag: timer is still active")
+		}
+	}
+	if !other.Active() {
+		t.Errorf("stop by tag: timer of other tag was stopped")
+	}
+	if n := StopByTag(tenant("a")); n != 0 {
+		t.Errorf("stop by tag: invalid count %v", n)
+	}
+
+	// A reset timer keeps its tag.
+	timers[1].Reset(time.Hour)
+	if n := StopByTag(tenant("a")); n != 1 {
+		t.Errorf("stop by tag: invalid count after reset %v", n)
+	}
+}
+
+func TestStopByTagFired(t *testing.T) {
+	timer := NewTimerTagged(0, "fired")
+	<-timer.C
+
+	tagMutex.Lock()
+	_, ok := tags["fired"]
+	tagMutex.Unlock()
+	if ok {
+		t.Errorf("stop by tag: fired timer was not removed from the index")
+	}
+	if n := StopByTag("fired"); n != 0 {
+		t.Errorf("stop by tag: invalid count %v", n)
+	}
+}
+
+func TestFairTags(t *testing.T) {
+	SetFairTags(true)
+	defer SetFairTags(false)
+
+	clk := &frozenClock{FakeClock: NewFakeClock(time.Unix(0, 0))}
+	var order []string
+	newTimer := func(tag string) *Timer {
+		return NewTimerWithOptions(time.Second, WithClock(clk), WithTag(tag), WithOnFire(func(time.Time) {
+			order = append(order, tag)
+		}))
+	}
+	for i := 0; i < 1000; i++ {
+		newTimer("a")
+	}
+	periodic := newTimer("c")
+	periodic.ResetPeriodic(time.Second)
+	newTimer("b")
+
+	// All timers expire at the same instant.
+	clk.Advance(time.Second)
+	periodic.s.wake()
+	if len(order) != 1002 {
+		t.Fatalf("fair tags: %d timers fired", len(order))
+	}
+	if i := slices.Index(order, "b"); i < 0 || i > 2 {
+		t.Errorf("fair tags: single timer of tag b fired at %d", i)
+	}
+	if !periodic.Active() {
+		t.Errorf("fair tags: periodic timer was not rescheduled")
+	}
+	periodic.Stop()
+}
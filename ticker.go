@@ -0,0 +1,60 @@
+package timer
+
+import (
+	"time"
+)
+
+// A Ticker holds a channel that delivers "ticks" of a clock at intervals.
+// A Ticker must be created with NewTicker.
+type Ticker struct {
+	C <-chan time.Time
+
+	t *Timer
+}
+
+// NewTicker returns a new Ticker containing a channel that will send the
+// current time on the channel after each tick. The period of the ticks is
+// specified by the duration argument. The duration d must be greater than
+// zero; if not, NewTicker will panic.
+//
+// Like Go's Ticker, ticks are dropped to make up for slow receivers:
+// the channel buffers at most one tick and further ticks are discarded
+// until the pending tick has been received. The ticker does not drift,
+// missed ticks are skipped and the next tick stays aligned to the period.
+// Stop the ticker to release associated resources.
+func NewTicker(d time.Duration) *Ticker {
+	if d <= 0 {
+		panic("timer: non-positive interval for NewTicker")
+	}
+	t := NewStoppedTimer()
+	resetTimer(t, d, d)
+	return &Ticker{
+		C: t.C,
+		t: t,
+	}
+}
+
+// Stop turns off a ticker. After Stop, no more ticks will be sent.
+// Stop does not close the channel, to prevent a concurrent goroutine
+// reading from the channel from seeing an erroneous "tick".
+func (t *Ticker) Stop() {
+	if t.t == nil {
+		panic("timer: Stop called on uninitialized Ticker")
+	}
+	delTimer(t.t)
+}
+
+// Reset stops a ticker and resets its period to the specified duration.
+// The next tick will arrive after the new period elapses. A pending tick
+// is drained from the channel, so that a receiver never observes a stale
+// tick after Reset. The duration d must be greater than zero; if not,
+// Reset will panic. A stopped ticker may be restarted with Reset.
+func (t *Ticker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("timer: non-positive interval for Ticker.Reset")
+	}
+	if t.t == nil {
+		panic("timer: Reset called on uninitialized Ticker")
+	}
+	resetTimer(t.t, d, d)
+}
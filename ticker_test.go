@@ -0,0 +1,91 @@
+package timer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTicker(t *testing.T) {
+	const period = 100 * time.Millisecond
+	start := time.Now()
+	ticker := NewTicker(period)
+	defer ticker.Stop()
+
+	for i := 1; i <= 5; i++ {
+		<-ticker.C
+		if elapsed := time.Since(start); elapsed < time.Duration(i)*period || elapsed > time.Duration(i)*period+period/2 {
+			t.Errorf("tick %v: took %v", i, elapsed)
+		}
+	}
+}
+
+func TestTickerDropsTicks(t *testing.T) {
+	const period = 50 * time.Millisecond
+	ticker := NewTicker(period)
+	defer ticker.Stop()
+
+	// Be a slow receiver. Only one tick must be buffered.
+	time.Sleep(10 * period)
+	if len(ticker.C) != 1 {
+		t.Errorf("ticker: channel should be filled")
+	}
+	<-ticker.C
+
+	select {
+	case <-ticker.C:
+		t.Errorf("ticker: ticks were not dropped")
+	default:
+	}
+}
+
+func TestTickerStop(t *testing.T) {
+	ticker := NewTicker(50 * time.Millisecond)
+	ticker.Stop()
+
+	select {
+	case <-ticker.C:
+		t.Errorf("failed to stop ticker")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestTickerReset(t *testing.T) {
+	ticker := NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	if len(ticker.C) != 1 {
+		t.Errorf("ticker: channel should be filled")
+	}
+
+	start := time.Now()
+	ticker.Reset(200 * time.Millisecond)
+	if len(ticker.C) != 0 {
+		t.Errorf("ticker reset: channel should be empty")
+	}
+
+	<-ticker.C
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond || elapsed > 300*time.Millisecond {
+		t.Errorf("ticker reset: took %v", elapsed)
+	}
+
+	// Restart a stopped ticker.
+	ticker.Stop()
+	start = time.Now()
+	ticker.Reset(100 * time.Millisecond)
+	<-ticker.C
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > 200*time.Millisecond {
+		t.Errorf("ticker reset after stop: took %v", elapsed)
+	}
+}
+
+func TestTickerPanic(t *testing.T) {
+	defer func() {
+		r := recover()
+		if r == nil || r.(string) != "timer: non-positive interval for NewTicker" {
+			t.Errorf("ticker: invalid panic")
+		}
+	}()
+
+	NewTicker(0)
+}
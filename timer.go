@@ -2,68 +2,891 @@
 package timer
 
 import (
+	"errors"
+	"math"
 	"time"
 )
 
+var (
+	// ErrTooManyTimers is returned by NewTimerErr if the limit set by
+	// SetMaxTimers is reached.
+	ErrTooManyTimers = errors.New("timer: too many timers")
+
+	// ErrTimeout is returned by Err if the timer fired,
+	// like context.DeadlineExceeded for a context.
+	ErrTimeout = errors.New("timer: deadline exceeded")
+
+	// ErrUninitialized is returned by ResetErr and StopErr if the Timer
+	// was not created by a constructor, like the zero Timer.
+	ErrUninitialized = errors.New("timer: uninitialized Timer")
+)
+
+// ActiveTimers returns the number of timers which are currently scheduled.
+// The value is intended for observability, for example to detect timers
+// which are never stopped. It might already be stale when returned.
+func ActiveTimers() int {
+	return countTimers()
+}
+
+// TotalCreated returns the number of timers created since the program
+// started or since the last ResetStats. A Timer is counted when it is
+// scheduled for the first time, a stopped Timer which is never scheduled
+// is not counted. A Timer obtained again by GetTimer counts as new.
+func TotalCreated() uint64 {
+	return totalCreated.Load()
+}
+
+// TotalFired returns the number of fires since the program started or
+// since the last ResetStats. Each fire of a periodic Timer is counted.
+func TotalFired() uint64 {
+	return totalFired.Load()
+}
+
+// TotalStopped returns the number of timers which were stopped before they
+// fired since the program started or since the last ResetStats. Only a Stop
+// which returns true is counted.
+func TotalStopped() uint64 {
+	return totalStopped.Load()
+}
+
+// ResetStats resets the counters returned by TotalCreated, TotalFired and
+// TotalStopped to zero. The counters are monotonic in between, which lets
+// dashboards compute rates of timers.
+func ResetStats() {
+	totalCreated.Store(0)
+	totalFired.Store(0)
+	totalStopped.Store(0)
+}
+
+// PendingCallbacks returns the number of timers created by AfterFunc which
+// are currently scheduled, a subset of ActiveTimers. A Stop which returns
+// true decrements it, as the callback is never called.
+// It visits all scheduled timers and is intended for diagnostics.
+// The value might already be stale when returned.
+func PendingCallbacks() int {
+	return countCallbacks()
+}
+
+// ShiftAll adds delta to the wake up time of all scheduled timers at once.
+// A positive delta delays all timers, a negative delta brings them forward
+// and fires the timers which are expired then. It is the global counterpart
+// of Pause and Resume. Paused timers are not affected.
+//
+// The order of the timers is preserved, hence the heaps do not need to be
+// restored and ShiftAll is O(n) for n timers.
+func ShiftAll(delta time.Duration) {
+	shiftTimers(delta)
+}
+
+// StopOlderThan stops all scheduled timers which were created more than
+// age ago and returns the number of stopped timers. The creation time of a
+// Timer is the time it was scheduled for the first time, later resets do not
+// renew it. The deadline of a timer does not matter: a timer created just
+// now is kept even if it fires in a year.
+//
+// StopOlderThan is a last resort janitor for timers which were leaked by
+// long-running programs. Paused timers are not affected.
+func StopOlderThan(age time.Duration) int {
+	return stopTimersOlder(age)
+}
+
+// Flush fires all timers which expired at the time of the call and returns
+// as soon as their fires were delivered. Timers which expire later are not
+// waited for. Flush is intended for tests and tools, which can then check
+// the outcome of a burst of expired timers without sleeping.
+//
+// The functions of AfterFunc timers are started, but might not have
+// returned yet. Timers created in synctest mode are not flushed.
+func Flush() {
+	flushTimers()
+}
+
+// IsIdle reports whether no timers are scheduled.
+// The value might already be stale when returned.
+func IsIdle() bool {
+	return countTimers() == 0
+}
+
+// Shutdown stops the background timer routines which have no timers
+// scheduled and waits until they exited. It returns true if no timer
+// routine is running anymore, false if timers are still scheduled.
+//
+// Shutdown is safe to call at any time: timers created or reset afterwards
+// restart their timer routine on demand. This lets short-lived programs and
+// leak detectors observe a clean exit without lingering goroutines.
+func Shutdown() bool {
+	return shutdownShards()
+}
+
+// SnapshotDeadlines returns the wake up times of up to n of the timers
+// which expire next, in ascending order. The timers are not modified.
+//
+// SnapshotDeadlines copies and sorts the wake up times of all scheduled
+// timers, which is O(m log m) for m timers. It is intended for diagnostics,
+// like a debug handler, and not for the hot path.
+func SnapshotDeadlines(n int) []time.Time {
+	if n <= 0 {
+		return nil
+	}
+	ws := timerDeadlines()
+	if len(ws) > n {
+		ws = ws[:n:n]
+	}
+	return ws
+}
+
+// NextDeadline returns the wake up time of the timer which expires next
+// and false if no timer is scheduled. Other event loops can use it to align
+// their own wake ups to the timers. The result is a snapshot which might
+// change immediately after the return.
+func NextDeadline() (time.Time, bool) {
+	return timerDeadline(false)
+}
+
+// FarthestDeadline returns the wake up time of the timer which expires last
+// and false if no timer is scheduled. It visits all scheduled timers and
+// is intended for diagnostics. The result is a snapshot which might change
+// immediately after the return.
+func FarthestDeadline() (time.Time, bool) {
+	return timerDeadline(true)
+}
+
+// SetRunnerCount spreads timers created afterwards across n timer routines,
+// each with its own heap, lock and goroutine. A burst of timers expiring at
+// the same instant is then fired by n routines in parallel. Timers created
+// before stay with their routine until they are stopped or fired.
+// The default is GOMAXPROCS at program start. n is at least 1.
+//
+// SetRunnerCount keeps a timing wheel set by UseTimingWheel.
+// It should be called during program initialization.
+func SetRunnerCount(n int) {
+	setShards(newShardsLike(*shards.Load(), n))
+}
+
+// SetMaxTimers limits the number of scheduled timers for NewTimerErr to n,
+// which acts as a circuit breaker for leaking timers. Timers are counted
+// as long as they are scheduled, fired and stopped timers are not counted.
+// The limit is checked when a timer is created, hence concurrent calls to
+// NewTimerErr might exceed it slightly. Other constructors ignore the limit.
+// A limit of zero, the default, disables it.
+func SetMaxTimers(n int) {
+	maxTimers.Store(int64(n))
+}
+
+// NewTimerErr creates a new Timer like NewTimer. It returns
+// ErrTooManyTimers if the limit set by SetMaxTimers is reached.
+func NewTimerErr(d time.Duration) (*Timer, error) {
+	if max := maxTimers.Load(); max > 0 && int64(countTimers()) >= max {
+		return nil, ErrTooManyTimers
+	}
+	return NewTimer(d), nil
+}
+
+// SetStrictMode enables or disables the strict mode, which detects misuse
+// of timers at the cost of additional locking. It is intended for
+// development builds. In strict mode
+//
+//   - any method which changes a Timer panics if the Timer was returned
+//     to the pool by PutTimer and was not obtained again by GetTimer,
+//     including a second PutTimer;
+//   - WaitContext and Fires panic if the Timer is stopped, that is neither
+//     scheduled, nor paused, nor has a pending value, because receiving
+//     from its channel would block forever. Timers created with
+//     WithCloseOnStop are exempt.
+//
+// A plain receive from the channel of a stopped Timer can not be detected.
+// Strict mode is disabled by default.
+func SetStrictMode(enabled bool) {
+	strictMode.Store(enabled)
+}
+
+// SetTimeScale runs the time of all timers factor times as fast as the real
+// time. A factor of 2 fires a Timer created with NewTimer(2*time.Second)
+// after one second of real time. The remaining durations and periods of
+// scheduled timers are rescaled as well. A factor of 1, the default,
+// disables scaling. SetTimeScale panics if factor is not a positive number.
+//
+// Only relative durations passed to constructors and methods are scaled,
+// absolute times like the one passed to ResetAt are not. Durations returned
+// by a Timer, like Remaining, and the remaining duration of paused timers
+// are real durations. Scaled durations saturate instead of overflowing.
+//
+// SetTimeScale is a package-wide knob to accelerate tests of timeout-heavy
+// code. Unlike a Clock, it does not require changes to the code under test.
+func SetTimeScale(factor float64) {
+	if !(factor > 0) || math.IsInf(factor, 1) {
+		panic("timer: SetTimeScale called with invalid factor")
+	}
+	setTimeScale(factor)
+}
+
+// SetSynctestMode enables or disables the synctest mode, which makes
+// timers compatible with the fake time of a testing/synctest bubble.
+//
+// Timers are normally fired by shared background routines, which live
+// outside of any bubble, hence they neither see the fake time nor advance
+// with it. In synctest mode each Timer created afterwards is fired by
+// runtime timers which are created by the goroutines creating and
+// resetting it. Timers created inside a bubble then follow its fake time.
+// A Timer must not be used outside of the bubble it was created in.
+//
+// Synctest mode is intended for tests only, as it costs a runtime timer
+// per Timer. Package-wide functions, like ActiveTimers or StopOlderThan,
+// do not see the timers created in synctest mode. Timers created with a
+// custom Clock are not affected. Synctest mode is disabled by default.
+func SetSynctestMode(enabled bool) {
+	synctestMode.Store(enabled)
+}
+
+// SetCoalesceWindow rounds the wake up time of timers scheduled afterwards
+// up to the next multiple of d. Timers expiring within the same window are
+// fired together in a single wake up, which reduces wake ups for many timers
+// with similar deadlines. Timers fire up to d late, but never early.
+// The follow-up ticks of periodic timers are not rounded.
+// A window of zero, the default, disables coalescing.
+func SetCoalesceWindow(d time.Duration) {
+	coalesceWindow.Store(int64(d))
+}
+
+// SetLatencyRecorder sets a function which is called for each fire with the
+// time the timer was scheduled to fire and the actual time it fired. The
+// difference is the delay caused by the timer routine, for example by
+// goroutine scheduling or GC pauses. The actual time is the value sent on
+// the timer's channel, as for Go's timers.
+// f is called by the timer routine while the timer heap is locked, hence it
+// must be fast and must not block. Pass nil to remove the recorder, which
+// is the default.
+func SetLatencyRecorder(f func(scheduled, actual time.Time)) {
+	if f == nil {
+		latencyRecorder.Store(nil)
+		return
+	}
+	latencyRecorder.Store(&f)
+}
+
+// SetCallbackPanicHandler recovers panics of functions passed to AfterFunc
+// and calls h with the recovered value and the stack trace of the panic.
+// A recovered panic is dropped after h returns; h may panic itself to
+// crash the program anyway. h is called in the goroutine of the panicking
+// function.
+//
+// Pass nil to remove the handler. By default, a panic is not recovered and
+// crashes the program, like a panic in any other goroutine.
+func SetCallbackPanicHandler(h func(recovered interface{}, stack []byte)) {
+	if h == nil {
+		callbackPanicHandler.Store(nil)
+		return
+	}
+	callbackPanicHandler.Store(&h)
+}
+
+// SetRunnerPanicHandler calls h with panics which occur while a timer is
+// fired, for example by a function passed to WithOnFire or by a channel
+// which was closed by someone else. Such a panic is always recovered: the
+// offending timer is dropped as if it fired for the last time and the
+// timer routine keeps serving all other timers. h is called while the
+// timer heap is locked, hence it must not block and must not call methods
+// of any Timer. Pass nil to remove the handler, which is the default.
+func SetRunnerPanicHandler(h func(recovered interface{})) {
+	if h == nil {
+		runnerPanicHandler.Store(nil)
+		return
+	}
+	runnerPanicHandler.Store(&h)
+}
+
 // The Timer type represents a single event. When the Timer expires,
 // the current time will be sent on C, unless the Timer was created by AfterFunc.
-// A Timer must be created with NewTimer. NewStoppedTimer or AfterFunc.
+// The value is the moment the timer routine actually fired the Timer, which
+// is never before and might be later than the scheduled time.
+// A Timer must be created with NewTimer, NewStoppedTimer or AfterFunc.
+//
+// The methods of a Timer are safe for concurrent use. All state is changed
+// while the timer heap is locked, hence concurrent calls to Reset end up
+// with the Timer scheduled exactly once at one of the requested times.
+//
+// Timers of the same timer routine, like timers sharing a Clock, which
+// expire at the same time fire in the order they were scheduled. Timers of
+// different routines or on a timing wheel are not ordered.
 type Timer struct {
-	C <-chan time.Time
+	C   <-chan time.Time
+	Sig <-chan struct{} // Set instead of C if created with WithSignalChan.
+
+	s       *shard        // shard holding the timer.
+	i       int           // heap index or index within the wheel bucket.
+	b       int           // wheel bucket index.
+	when    time.Time     // Timer wakes up at when.
+	start   time.Time     // Timer was scheduled at start.
+	created time.Time     // Timer was scheduled for the first time at created.
+	period  time.Duration // If greater than zero, the timer fires every period.
+	dur     time.Duration // Duration of the last relative schedule, used by Touch.
 
-	i    int       // heap index.
-	when time.Time // Timer wakes up at when.
+	fired     bool          // Timer fired since it was scheduled.
+	paused    bool          // Timer was removed from the heap by Pause.
+	remaining time.Duration // Remaining duration of a paused timer.
+	prio      int           // Lower priorities fire first among equal wake up times.
+	seq       uint64        // Schedule sequence number, earlier schedules fire first among equal priorities.
+	tag       interface{}   // Key of the timer in the tag index, if not nil.
+	afterFunc bool          // Timer was created by AfterFunc.
+	pooled    bool          // Timer was returned to the pool by PutTimer.
+	notify    chan struct{} // Signaled without blocking after each fire if not nil.
+	done      chan struct{} // Closed by Stop if created with WithDoneChan.
+	fn        func()        // Function of an AfterFunc timer, accessed in a locked context.
+
+	minInterval time.Duration // Minimum duration between two fires.
+	lastFire    time.Time     // Time of the last fire if minInterval is set.
 
 	// f is called in a locked context on timeout. This function must not block
 	// and must behave well-defined.
 	f func(t *time.Time)
 
 	// reset is called in a locked context. This function must not block
-	// and must behave well-defined.
-	reset func()
+	// and must behave well-defined. It returns true if a value was drained.
+	reset func() bool
+
+	// stop is optional and called in a locked context by Stop.
+	// This function must not block and must behave well-defined.
+	stop func()
+
+	// cancel is optional and called in a locked context by StopWithReason
+	// after stop. It sends the zero time without blocking.
+	cancel func()
+	reason error // Reason of the last StopWithReason.
+
+	// redirect is optional and called in a locked context by ResetChan.
+	// It changes the channel the timer sends to.
+	redirect func(c chan time.Time)
+
+	// next is optional and called in a locked context after each fire.
+	// It returns the next wake up time, or the zero time to remove the
+	// timer from the heap, and replaces the period.
+	next func(now time.Time) time.Time
 }
 
 // NewTimer creates a new Timer that will send the current time on its
 // channel after at least duration d.
+//
+// Timers created by NewTimer are spread over several timer routines, see
+// SetRunnerCount, hence timers which expire at the same time might fire in
+// any order. Timers created by NewTimerPrio with equal priorities share a
+// single timer routine and fire in the order they were scheduled.
 func NewTimer(d time.Duration) *Timer {
 	t := NewStoppedTimer()
 	addTimer(t, d)
 	return t
 }
 
+// After waits for the duration to elapse and then sends the current time
+// on the returned channel. It is equivalent to NewTimer(d).C.
+// The underlying Timer is not released until it fires. If efficiency is
+// a concern, use NewTimer instead and call Timer.Stop if the timer is
+// no longer needed.
+func After(d time.Duration) <-chan time.Time {
+	return NewTimer(d).C
+}
+
+// AfterStopped behaves like After, but additionally returns a function
+// which stops the underlying Timer. Call stop once the channel is not
+// needed anymore, to release the Timer before it fires. stop reports
+// like Timer.Stop whether the timer was stopped before firing.
+func AfterStopped(d time.Duration) (c <-chan time.Time, stop func() bool) {
+	t := NewTimer(d)
+	return t.C, t.Stop
+}
+
+// NewTimerPrio creates a new Timer like NewTimer with the priority prio.
+// Among timers created by NewTimerPrio which expire at the same time, the
+// timers with lower priorities fire first. These timers share a single
+// timer routine to make the order deterministic. Timers with equal
+// priorities fire in the order they were scheduled. The priority is ignored
+// by timers on a timing wheel, which fire in arbitrary order within a tick.
+func NewTimerPrio(d time.Duration, prio int) *Timer {
+	t := newStoppedTimer(options{})
+	if !t.s.private {
+		t.s = (*shards.Load())[0]
+	}
+	t.prio = prio
+	addTimer(t, d)
+	return t
+}
+
+// NewUrgentTimer creates a new Timer like NewTimer for critical timers,
+// like watchdogs. Urgent timers have their own heap and timer routine,
+// which serves no other timers. A backlog of ordinary fires, for example
+// of a burst of timers expiring together or of slow WithOnFire functions,
+// does not delay them.
+//
+// Urgent timers should be rare, as they share a single timer routine.
+// SetRunnerCount and UseTimingWheel do not affect them.
+func NewUrgentTimer(d time.Duration) *Timer {
+	t := newStoppedTimer(options{})
+	if !t.s.private {
+		t.s = urgentShard()
+	}
+	addTimer(t, d)
+	return t
+}
+
+// NewTimers creates a new Timer for each duration in ds.
+// It behaves like calling NewTimer for each duration, but adds all
+// timers at once to the timer heap. The returned timers are independent.
+func NewTimers(ds []time.Duration) []*Timer {
+	ts := make([]*Timer, len(ds))
+	for i := range ts {
+		ts[i] = NewStoppedTimer()
+	}
+	addTimers(ts, ds)
+	return ts
+}
+
+// StopAll stops all timers like calling Stop for each of them, but locks
+// the timer heap only once. It returns the result of Stop for each timer
+// in order. The channels are not drained.
+func StopAll(timers []*Timer) []bool {
+	for _, t := range timers {
+		if t.f == nil {
+			panic("timer: StopAll called on uninitialized Timer")
+		}
+	}
+	return stopTimers(timers)
+}
+
+// WaitAny blocks until the first of the given timers fires, receives its
+// value and returns its index and the received time. All other timers are
+// stopped and drained. If several timers fired before WaitAny observes them,
+// the timer with the lowest index wins. A value which is already pending
+// counts as a fire, hence WaitAny returns immediately in that case.
+//
+// WaitAny does not use reflection or a goroutine per timer. The timers must
+// have a buffered channel and must not be received from or waited on by
+// another WaitAny concurrently. If no timer is scheduled, WaitAny blocks
+// forever.
+func WaitAny(timers ...*Timer) (index int, t time.Time) {
+	if len(timers) == 0 {
+		panic("timer: WaitAny called without timers")
+	}
+	for _, t := range timers {
+		if t.f == nil || t.C == nil {
+			panic("timer: WaitAny called on uninitialized Timer")
+		}
+	}
+
+	notify := make(chan struct{}, 1)
+	setTimersNotify(timers, notify)
+	defer setTimersNotify(timers, nil)
+
+	for {
+		for i, tm := range timers {
+			select {
+			case v := <-tm.C:
+				for j, other := range timers {
+					if j != i {
+						other.StopAndDrain()
+					}
+				}
+				return i, v
+			default:
+			}
+		}
+		<-notify
+	}
+}
+
+// NewTimerAt creates a new Timer that will send the current time on its
+// channel at the absolute time at. If at is in the past, the timer fires
+// immediately.
+//
+// The absolute time is converted to the monotonic clock once during this call.
+// Subsequent changes of the system wall clock do not move the deadline.
+func NewTimerAt(at time.Time) *Timer {
+	t := NewStoppedTimer()
+	addTimerAt(t, monotonicAt(t, at))
+	return t
+}
+
 // NewStoppedTimer creates a new stopped Timer.
 func NewStoppedTimer() *Timer {
-	c := make(chan time.Time, 1)
+	return newStoppedTimer(options{})
+}
+
+func newStoppedTimer(o options) *Timer {
+	var t *Timer
+	if o.signal {
+		t = newSignalTimer(o)
+	} else {
+		t = newChanTimer(o)
+	}
+	t.s = o.shard()
+	t.minInterval = o.minInterval
+	t.tag = o.tag
+	if o.onFire != nil && o.callbackPool != nil {
+		t.f = o.callbackPool.onFire(t.f, o.onFire)
+	} else if o.onFire != nil {
+		f, onFire := t.f, o.onFire
+		t.f = func(t *time.Time) {
+			f(t)
+			onFire(*t)
+		}
+	}
+	if o.doneChan {
+		t.done = make(chan struct{})
+		stop := t.stop
+		t.stop = func() {
+			if stop != nil {
+				stop()
+			}
+			if !isClosed(t.done) {
+				close(t.done)
+			}
+		}
+	}
+	return t
+}
+
+// Create a Timer which sends the fire time on C.
+func newChanTimer(o options) *Timer {
+	var c chan time.Time
+	if o.unbuffered {
+		c = make(chan time.Time)
+	} else {
+		c = make(chan time.Time, 1)
+	}
+	// closed is only accessed in a locked context.
+	var closed bool
+	truncate := o.truncate
 	t := &Timer{
 		C: c,
 		f: func(t *time.Time) {
+			if closed {
+				return
+			}
+			v := *t
+			if truncate > 0 {
+				v = v.Truncate(truncate)
+			}
+			// Don't block.
+			select {
+			case c <- v:
+			default:
+			}
+		},
+		reset: func() bool {
+			if closed {
+				return false
+			}
+			// Empty the channel if filled.
+			select {
+			case <-c:
+				return true
+			default:
+				return false
+			}
+		},
+	}
+	t.cancel = func() {
+		if closed {
+			return
+		}
+		select {
+		case c <- time.Time{}:
+		default:
+		}
+	}
+	t.redirect = func(nc chan time.Time) {
+		c = nc
+		closed = false
+	}
+	if o.closeOnStop {
+		t.stop = func() {
+			if !closed {
+				closed = true
+				close(c)
+			}
+		}
+	}
+	return t
+}
+
+// Create a Timer which sends an empty struct on Sig.
+func newSignalTimer(o options) *Timer {
+	var c chan struct{}
+	if o.unbuffered {
+		c = make(chan struct{})
+	} else {
+		c = make(chan struct{}, 1)
+	}
+	// closed is only accessed in a locked context.
+	var closed bool
+	t := &Timer{
+		Sig: c,
+		f: func(*time.Time) {
+			if closed {
+				return
+			}
 			// Don't block.
 			select {
-			case c <- *t:
+			case c <- struct{}{}:
 			default:
 			}
 		},
-		reset: func() {
+		reset: func() bool {
+			if closed {
+				return false
+			}
 			// Empty the channel if filled.
 			select {
 			case <-c:
+				return true
 			default:
+				return false
 			}
 		},
 	}
+	if o.closeOnStop {
+		t.stop = func() {
+			if !closed {
+				closed = true
+				close(c)
+			}
+		}
+	}
 	return t
 }
 
+// AfterFunc waits for the duration to elapse and then calls f
+// in its own goroutine. It returns a Timer that can
+// be used to cancel the call using its Stop method.
+// The returned Timer's C field is nil.
+//
+// Stop and Reset are safe to use on the returned Timer: if Stop returns true,
+// f will not be called. Reset reschedules f for a new call after duration d.
+// Note that f runs in its own goroutine and is not synchronized with Stop or
+// Reset. A call to f which already started may overlap with a subsequent
+// Reset and even with the next call to f.
+//
+// f may call Reset or Stop on its own Timer. This does not deadlock,
+// because f is not called while the timer heap is locked. A Timer can be
+// scheduled only once, hence a Reset within f reschedules the Timer exactly
+// once, even if Reset was called concurrently.
+func AfterFunc(d time.Duration, f func()) *Timer {
+	t := newFuncTimer(f)
+	addTimer(t, d)
+	return t
+}
+
+// AddAbsolute is a low-level primitive for scheduler authors. It calls
+// f(arg) in its own goroutine at the absolute time whenNanos, given in
+// nanoseconds since the Unix epoch. If whenNanos is in the past, f is
+// called immediately. The returned Timer's C field is nil.
+//
+// The Timer behaves like one created by AfterFunc: Stop, Reset and
+// ResetFunc are safe to use on it, and ResetFunc replaces the call of
+// f(arg). As with NewTimerAt, the deadline is converted to the monotonic
+// clock once during this call.
+//
+// Most programs should use AfterFunc or NewTimerAt instead.
+func AddAbsolute(whenNanos int64, f func(arg interface{}), arg interface{}) *Timer {
+	t := newFuncTimer(func() { f(arg) })
+	addTimerAt(t, monotonicAt(t, time.Unix(0, whenNanos)))
+	return t
+}
+
+// Create a stopped Timer without channel which calls fn in its own
+// goroutine on each fire.
+func newFuncTimer(fn func()) *Timer {
+	t := &Timer{
+		s:         pickShard(),
+		reset:     func() bool { return false },
+		afterFunc: true,
+		fn:        fn,
+	}
+	t.f = func(*time.Time) {
+		go runCallback(t.fn)
+	}
+	return t
+}
+
+// ResetFunc changes the timer created by AfterFunc to call f instead after
+// duration d. It returns true if the timer had been active, false if the
+// timer had expired or been stopped.
+//
+// The callback and the deadline are changed at once: if the previous
+// callback had not been started yet, it is never called and only f is
+// called after d. A previous callback which was already started keeps
+// running in its own goroutine and may overlap with f.
+func (t *Timer) ResetFunc(d time.Duration, f func()) bool {
+	if t.f == nil {
+		panic("timer: ResetFunc called on uninitialized Timer")
+	}
+	checkStrict(t, "ResetFunc")
+	if !t.afterFunc {
+		panic("timer: ResetFunc called on Timer not created by AfterFunc")
+	}
+	return resetTimerFunc(t, d, f)
+}
+
 // Stop prevents the Timer from firing.
 // It returns true if the call stops the timer,
 // false if the timer has already expired or been stopped.
 // Stop does not close the channel, to prevent a read from
-// the channel succeeding incorrectly.
+// the channel succeeding incorrectly, unless the Timer was created
+// with the WithCloseOnStop option.
+//
+// For a Timer created with AfterFunc(d, f), if t.Stop returns false, then the
+// timer has already expired and the function f has been started in its own
+// goroutine. Stop does not wait for f to complete before returning.
 func (t *Timer) Stop() (wasActive bool) {
 	if t.f == nil {
 		panic("timer: Stop called on uninitialized Timer")
 	}
-	return delTimer(t)
+	checkStrict(t, "Stop")
+	return stopTimer(t)
+}
+
+// Status is the result of StopStatus.
+type Status int
+
+const (
+	// Stopped means the timer was active and has been stopped.
+	// No value was delivered to the channel.
+	Stopped Status = iota
+
+	// AlreadyFired means the timer has already fired since it was last
+	// scheduled. A value might be pending in the channel and must be drained
+	// before the channel is received from again.
+	AlreadyFired
+
+	// NotActive means the timer was neither active nor fired, because it
+	// was never scheduled or has already been stopped.
+	NotActive
+)
+
+// String returns the name of the status.
+func (s Status) String() string {
+	switch s {
+	case Stopped:
+		return "stopped"
+	case AlreadyFired:
+		return "already fired"
+	case NotActive:
+		return "not active"
+	default:
+		return "unknown"
+	}
+}
+
+// StopStatus prevents the Timer from firing like Stop, but reports
+// precisely in which state the timer was. Only if AlreadyFired is
+// returned, a value might be pending in t.C.
+func (t *Timer) StopStatus() Status {
+	if t.f == nil {
+		panic("timer: StopStatus called on uninitialized Timer")
+	}
+	checkStrict(t, "StopStatus")
+	return stopTimerStatus(t)
+}
+
+// StopWithReason prevents the Timer from firing like Stop and records err
+// as the reason. If the call stops the timer, the zero Time is sent on t.C,
+// which wakes up a receiver and lets it tell the cancellation from a fire.
+// The receiver obtains the reason by StopReason.
+// If the timer already fired, nothing is sent, hence a receiver never
+// observes both a fire and a cancellation. It returns true if the call
+// stops the timer, false if the timer has already expired or been stopped.
+//
+// The zero Time is not sent if the Timer was created by AfterFunc or
+// the channel was closed by WithCloseOnStop.
+func (t *Timer) StopWithReason(err error) bool {
+	if t.f == nil {
+		panic("timer: StopWithReason called on uninitialized Timer")
+	}
+	checkStrict(t, "StopWithReason")
+	return stopTimerReason(t, err)
+}
+
+// Done returns a channel which is closed when the timer is stopped, if the
+// Timer was created with WithDoneChan, or nil otherwise. A Reset after Stop
+// replaces the channel, hence Done should be called after each Reset.
+func (t *Timer) Done() <-chan struct{} {
+	if t.f == nil {
+		panic("timer: Done called on uninitialized Timer")
+	}
+	return timerDone(t)
+}
+
+// StopReason returns the reason passed to StopWithReason, if the timer was
+// stopped by StopWithReason since it was last scheduled, or nil.
+func (t *Timer) StopReason() error {
+	if t.f == nil {
+		panic("timer: StopReason called on uninitialized Timer")
+	}
+	return timerReason(t)
+}
+
+// StopAndDrain prevents the Timer from firing and clears the channel t.C.
+// It returns true if the call stops the timer,
+// false if the timer has already expired or been stopped.
+// A value which was already delivered to t.C is removed.
+// Unlike a manual drain after Stop, this never blocks and does not race
+// with the firing timer.
+func (t *Timer) StopAndDrain() (wasActive bool) {
+	if t.f == nil {
+		panic("timer: StopAndDrain called on uninitialized Timer")
+	}
+	checkStrict(t, "StopAndDrain")
+	return delTimerAndReset(t)
+}
+
+// Drain removes a pending value from the channel t.C without blocking.
+// It returns true if a value was removed.
+// Drain is synchronized with the firing timer, hence a value is either
+// removed or delivered afterwards, but never lost in between.
+// Drain is only meaningful after Stop returned false, because an active
+// timer delivers its value later.
+func (t *Timer) Drain() bool {
+	if t.f == nil {
+		panic("timer: Drain called on uninitialized Timer")
+	}
+	checkStrict(t, "Drain")
+	return drainTimer(t)
+}
+
+// ResetErr changes the timer to expire after duration d like Reset, but
+// returns ErrUninitialized instead of panicking if the Timer was not created
+// by a constructor. It suits libraries which must not panic across their
+// API boundary. The result of Reset is dropped, use Reset if it matters.
+func (t *Timer) ResetErr(d time.Duration) error {
+	if t.f == nil {
+		return ErrUninitialized
+	}
+	checkStrict(t, "ResetErr")
+	resetTimer(t, d, 0)
+	return nil
+}
+
+// StopErr prevents the Timer from firing like Stop, but returns
+// ErrUninitialized instead of panicking if the Timer was not created
+// by a constructor.
+func (t *Timer) StopErr() (wasActive bool, err error) {
+	if t.f == nil {
+		return false, ErrUninitialized
+	}
+	checkStrict(t, "StopErr")
+	return stopTimer(t), nil
+}
+
+// TryReceive receives a pending value from the channel t.C without
+// blocking. It returns the fire time and true if a value was pending,
+// or the zero Time and false otherwise, including for a closed channel.
+// A cancellation sent by StopWithReason is returned as the zero Time
+// and true. Like Drain, TryReceive is synchronized with the firing timer.
+// It does not change the sticky state reported by Fired.
+func (t *Timer) TryReceive() (time.Time, bool) {
+	if t.f == nil {
+		panic("timer: TryReceive called on uninitialized Timer")
+	}
+	checkStrict(t, "TryReceive")
+	return tryReceiveTimer(t)
 }
 
 // Reset changes the timer to expire after duration d.
@@ -71,9 +894,348 @@ func (t *Timer) Stop() (wasActive bool) {
 // false if the timer had expired or been stopped.
 // The channel t.C is cleared and calling t.Reset() behaves as creating a
 // new Timer.
+//
+// After Reset returns, t.C holds no value of a fire from before the call.
+// This holds even for a fire which races with the call: fires are sent and
+// drained while the timer heap is locked, hence a concurrent fire is either
+// sent before Reset drains t.C or not at all.
+//
+// A duration of zero or less expires the timer now. The fire goes through
+// the timer heap like any other, hence it is ordered after the timers which
+// expired before and does not jump the queue. Negative durations are not
+// ordered before a duration of zero.
 func (t *Timer) Reset(d time.Duration) bool {
 	if t.f == nil {
 		panic("timer: Reset called on uninitialized Timer")
 	}
-	return resetTimer(t, d)
+	checkStrict(t, "Reset")
+	return resetTimer(t, d, 0)
+}
+
+// ResetAndWait changes the timer to expire after duration d like Reset and
+// blocks until it fires. It returns the time of the new fire: Reset drains
+// a stale value of an earlier fire, hence it is never returned.
+// A duration of zero or less returns immediately.
+//
+// The new fire must not be received from t.C concurrently, otherwise
+// ResetAndWait blocks until the next fire. ResetAndWait panics if the Timer
+// was created by AfterFunc, which has no channel to wait on.
+func (t *Timer) ResetAndWait(d time.Duration) time.Time {
+	if t.f == nil {
+		panic("timer: ResetAndWait called on uninitialized Timer")
+	}
+	if t.C == nil {
+		panic("timer: ResetAndWait called on Timer without channel")
+	}
+	checkStrict(t, "ResetAndWait")
+	resetTimer(t, d, 0)
+	return <-t.C
+}
+
+// ExpireNow fires the timer immediately in the calling goroutine, as if it
+// expired right now. A pending value is cleared first, hence the value on
+// t.C is the current time when ExpireNow returns. A periodic timer keeps
+// firing every period from now on. It returns true if the timer had been
+// active, false if the timer had expired or been stopped.
+//
+// Unlike Reset(0), the fire does not wait for the timer routine, which is
+// handy to trigger timeout code paths in tests deterministically.
+func (t *Timer) ExpireNow() bool {
+	if t.f == nil {
+		panic("timer: ExpireNow called on uninitialized Timer")
+	}
+	checkStrict(t, "ExpireNow")
+	return expireTimer(t)
+}
+
+// ExtendBy moves the deadline of the active timer by delta, relative to
+// its current deadline instead of to now like Reset. Several calls
+// accumulate, which suits sliding deadlines of AfterFunc timers. A negative
+// delta brings the deadline forward, but not before now. The deadline
+// saturates instead of overflowing. Unlike Reset, ExtendBy does not drain
+// t.C and keeps the fired and elapsed state. It returns true if the timer
+// was active and moved, false if the timer had expired or been stopped.
+func (t *Timer) ExtendBy(delta time.Duration) bool {
+	if t.f == nil {
+		panic("timer: ExtendBy called on uninitialized Timer")
+	}
+	checkStrict(t, "ExtendBy")
+	return extendTimer(t, delta)
+}
+
+// ResetChan changes the timer to expire after duration d like Reset and
+// switches the channel it sends to to c. A pending value is drained from the
+// old channel, c is not drained. t.C is set to c. It returns true if the
+// timer had been active, false if the timer had expired or been stopped.
+//
+// The switch happens while the timer heap is locked, hence a concurrent fire
+// is delivered either to the old channel before the call or to c afterwards.
+// Fires are sent without blocking: c should be buffered with a capacity of
+// at least one, otherwise a fire is dropped unless a receiver is waiting.
+// t.C must not be accessed concurrently with ResetChan.
+// ResetChan panics if c is nil or if the Timer was created by AfterFunc.
+func (t *Timer) ResetChan(d time.Duration, c chan time.Time) bool {
+	if t.f == nil {
+		panic("timer: ResetChan called on uninitialized Timer")
+	}
+	if t.redirect == nil {
+		panic("timer: ResetChan called on Timer without channel")
+	}
+	if c == nil {
+		panic("timer: ResetChan called with nil channel")
+	}
+	checkStrict(t, "ResetChan")
+	return resetTimerChan(t, d, c)
+}
+
+// Touch changes the timer to expire after the duration it was last
+// created or reset with, like Reset with that duration. A periodic timer
+// keeps its period. It returns true if the timer had been active, false if
+// the timer had expired or been stopped. Like Reset, Touch drains t.C.
+//
+// Touch is intended for sliding idle timeouts, which are pushed out on every
+// activity. The duration is the last one passed to NewTimer, Reset or any
+// other function taking a duration. Absolute times, like the one passed to
+// ResetAt, do not change it. A Timer which was first scheduled at an absolute
+// time, like by NewTimerAt or AddAbsolute, starts with the duration until
+// that time.
+func (t *Timer) Touch() bool {
+	if t.f == nil {
+		panic("timer: Touch called on uninitialized Timer")
+	}
+	checkStrict(t, "Touch")
+	return touchTimer(t)
+}
+
+// ResetChanFor changes the timer to expire after duration d like Reset and
+// returns a fresh channel, which delivers only the next fire. No value of an
+// earlier fire can be pending in it, not even one sent concurrently to the
+// reset. It returns the channel like ResetChan, which also sets t.C to it:
+// receiving from t.C after the call keeps working, but copies of the old
+// channel do not receive fires anymore.
+//
+// Each call allocates a new buffered channel, about 128 bytes on 64-bit
+// platforms. Use Reset in hot paths, which drains t.C without allocation.
+// ResetChanFor panics if the Timer was created by AfterFunc.
+func (t *Timer) ResetChanFor(d time.Duration) <-chan time.Time {
+	if t.f == nil {
+		panic("timer: ResetChanFor called on uninitialized Timer")
+	}
+	if t.redirect == nil {
+		panic("timer: ResetChanFor called on Timer without channel")
+	}
+	checkStrict(t, "ResetChanFor")
+	c := make(chan time.Time, 1)
+	resetTimerChan(t, d, c)
+	return c
+}
+
+// ResetKeepPending changes the timer to expire after duration d like Reset,
+// but does not clear the channel t.C. It returns true if the timer had been
+// active, false if the timer had expired or been stopped.
+//
+// Caution: a value of a fire before the call stays pending, hence the next
+// receive from t.C might return the old fire immediately. As long as it is
+// pending, the channel is full and the new fire is dropped.
+// This is the behavior of the standard library's Timer before Go 1.23.
+func (t *Timer) ResetKeepPending(d time.Duration) bool {
+	if t.f == nil {
+		panic("timer: ResetKeepPending called on uninitialized Timer")
+	}
+	checkStrict(t, "ResetKeepPending")
+	return resetTimerKeepPending(t, d)
+}
+
+// ResetPeriodic changes the timer to expire after duration d and
+// to expire again every d afterwards, until it is stopped or reset.
+// It behaves like Reset and clears the channel t.C, but keeps the Timer
+// and its channel, unlike a new Ticker. The duration d must be greater
+// than zero; if not, ResetPeriodic will panic.
+//
+// If the receiver falls behind, fires are dropped instead of accumulated,
+// because t.C holds at most one value. The timer stays aligned to its
+// period and periods which already elapsed are skipped.
+func (t *Timer) ResetPeriodic(d time.Duration) bool {
+	if t.f == nil {
+		panic("timer: ResetPeriodic called on uninitialized Timer")
+	}
+	checkStrict(t, "ResetPeriodic")
+	if d <= 0 {
+		panic("timer: non-positive interval for ResetPeriodic")
+	}
+	return resetTimer(t, d, d)
+}
+
+// ResetShorterOnly changes the timer to expire after duration d, but only
+// if the timer is active and expires earlier then, which enforces a maximum
+// deadline that can only be pulled in. It returns true if the timer was
+// reset, false if the deadline was left unchanged. A fired or stopped timer
+// is never rescheduled and a fired value is left in t.C.
+// The check and the reset are performed atomically.
+// A periodic timer becomes a one-shot timer when it is reset.
+func (t *Timer) ResetShorterOnly(d time.Duration) bool {
+	if t.f == nil {
+		panic("timer: ResetShorterOnly called on uninitialized Timer")
+	}
+	checkStrict(t, "ResetShorterOnly")
+	return resetTimerShorter(t, d)
+}
+
+// ResetIfActive changes the timer to expire after duration d, but only if
+// the timer is still active. It returns true if the timer was reset,
+// false if the timer had expired or been stopped. In that case the timer
+// is not rescheduled and a fired value is left in t.C.
+// The check and the reset are performed atomically.
+func (t *Timer) ResetIfActive(d time.Duration) bool {
+	if t.f == nil {
+		panic("timer: ResetIfActive called on uninitialized Timer")
+	}
+	checkStrict(t, "ResetIfActive")
+	return resetActiveTimer(t, d)
+}
+
+// ResetRemaining behaves like Reset and additionally returns the duration
+// which was remaining until the timer would have fired.
+// The remaining duration is zero if the timer had expired or been stopped.
+func (t *Timer) ResetRemaining(d time.Duration) (prev time.Duration, wasActive bool) {
+	if t.f == nil {
+		panic("timer: ResetRemaining called on uninitialized Timer")
+	}
+	checkStrict(t, "ResetRemaining")
+	return resetTimerRemaining(t, d)
+}
+
+// Pause stops the timer and remembers its remaining duration,
+// which is restored by Resume.
+// It returns false if the timer has already expired or been stopped.
+// Stop and Reset discard the paused state.
+func (t *Timer) Pause() bool {
+	if t.f == nil {
+		panic("timer: Pause called on uninitialized Timer")
+	}
+	checkStrict(t, "Pause")
+	return pauseTimer(t)
+}
+
+// Resume reschedules a paused timer to expire after its remaining duration
+// from now. It returns false if the timer was not paused.
+func (t *Timer) Resume() bool {
+	if t.f == nil {
+		panic("timer: Resume called on uninitialized Timer")
+	}
+	checkStrict(t, "Resume")
+	return resumeTimer(t)
+}
+
+// ResetAt changes the timer to expire at the absolute time at.
+// It behaves like Reset and clears the channel t.C.
+// If at is in the past, the timer fires immediately.
+// The same wall clock considerations as for NewTimerAt apply.
+func (t *Timer) ResetAt(at time.Time) bool {
+	if t.f == nil {
+		panic("timer: ResetAt called on uninitialized Timer")
+	}
+	checkStrict(t, "ResetAt")
+	return resetTimerAt(t, monotonicAt(t, at), 0)
+}
+
+// ResetAtEffective changes the timer to expire at the absolute time at like
+// ResetAt, and additionally returns the time the timer is scheduled to fire.
+// A time in the past is clamped to now and the coalesce window is applied.
+// The returned time carries a monotonic clock reading, like the deadlines
+// used internally. It returns true as second value if the timer had been
+// active, false if the timer had expired or been stopped.
+//
+// ResetAt keeps its signature for compatibility.
+func (t *Timer) ResetAtEffective(at time.Time) (effective time.Time, wasActive bool) {
+	if t.f == nil {
+		panic("timer: ResetAtEffective called on uninitialized Timer")
+	}
+	checkStrict(t, "ResetAtEffective")
+	return resetTimerAtEffective(t, at)
+}
+
+// Active reports whether the timer is scheduled to fire.
+// A timer is inactive as soon as it expired and was removed from the timer
+// heap, even if the value was not received from t.C yet.
+// The timer is not modified.
+func (t *Timer) Active() bool {
+	return timerActive(t)
+}
+
+// Fired reports whether the timer fired since it was last scheduled,
+// without receiving from t.C. Together with Remaining this allows to poll
+// a timer instead of receiving from its channel.
+//
+// Fired stays true after the value was received from t.C or drained by
+// Drain or StopAndDrain, so every Timer keeps a sticky fired state. A value is
+// pending in t.C only if it was not received yet, and it was dropped if
+// the timer is unbuffered and no receiver was waiting.
+// Reset clears the fired state. Stop does not.
+func (t *Timer) Fired() bool {
+	return timerFired(t)
+}
+
+// IsStopped reports whether the timer is neither scheduled to fire, nor
+// paused, nor has a pending value in t.C. A timer which fired but whose
+// value was not received yet is not stopped. A paused timer is not stopped
+// either, because Resume reschedules it. A stopped timer is safe to be reused.
+func (t *Timer) IsStopped() bool {
+	return timerStopped(t)
+}
+
+// String returns a description of the timer's state for debugging:
+// active with the remaining duration, paused, fired but not yet
+// received from t.C, or stopped.
+func (t *Timer) String() string {
+	return timerString(t)
+}
+
+// Clone returns a new independent Timer with its own channel, which is
+// scheduled at the same absolute wake up time and with the same period as t.
+// Unlike NewTimer(t.Remaining()), both timers fire at the same instant.
+// The clone of a timer which is not scheduled is stopped. The clone sends
+// the time on its channel, even if t was created by AfterFunc, and does
+// not inherit options of t except its clock.
+func (t *Timer) Clone() *Timer {
+	if t.f == nil {
+		panic("timer: Clone called on uninitialized Timer")
+	}
+	var o options
+	if t.s.rescheduleC == nil && !t.s.private {
+		o.clock = t.s.clock
+	}
+	c := newStoppedTimer(o)
+	c.prio = t.prio
+	cloneTimer(t, c)
+	return c
+}
+
+// Elapsed returns the duration since the timer was last scheduled and the
+// total duration it was scheduled for, which allows to display progress.
+// For a timer which expired or was stopped, elapsed equals total.
+// The total duration includes adjustments like the coalesce window.
+// A periodic timer reports the progress of the current period and a resumed
+// timer the progress of its remaining duration.
+func (t *Timer) Elapsed() (elapsed, total time.Duration) {
+	return timerElapsed(t)
+}
+
+// Remaining returns the duration until the timer fires.
+// Zero is returned if the timer has already expired or been stopped.
+func (t *Timer) Remaining() time.Duration {
+	when := timerWhen(t)
+	if when.IsZero() {
+		return 0
+	}
+	if d := when.Sub(t.s.clock.Now()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Deadline returns the time at which the timer fires.
+// The zero time is returned if the timer has already expired or been stopped.
+func (t *Timer) Deadline() time.Time {
+	return timerWhen(t)
 }
@@ -1,7 +1,12 @@
 package timer
 
 import (
+	"errors"
+	"math"
+	"runtime"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -108,6 +113,49 @@ func TestStoppedTimer(t *testing.T) {
 	}
 }
 
+func TestStoppedTimerReset(t *testing.T) {
+	for _, d := range []time.Duration{0, -time.Second} {
+		timer := NewStoppedTimer()
+		if timer.Reset(d) {
+			t.Errorf("stopped timer: was active is true for %v", d)
+		}
+		select {
+		case <-timer.C:
+		case <-time.After(time.Second):
+			t.Fatalf("stopped timer: reset to %v did not fire", d)
+		}
+	}
+
+	// Never added timers must not be mistaken for the heap root,
+	// which shares their zero heap index.
+	defer setShards(*shards.Load())
+	setShards(newShards(1))
+	s := (*shards.Load())[0]
+
+	root := NewTimer(time.Hour)
+	defer root.Stop()
+	n := ActiveTimers()
+
+	timer := NewStoppedTimer()
+	if timer.Stop() {
+		t.Errorf("stopped timer: stop of never added timer returned true")
+	}
+	for i := 0; i < 10; i++ {
+		wasActive := timer.Reset(time.Duration(i+1) * time.Hour)
+		if wasActive != (i > 0) {
+			t.Errorf("stopped timer: invalid was active %v on reset %v", wasActive, i)
+		}
+	}
+	if diff := ActiveTimers() - n; diff != 1 {
+		t.Errorf("stopped timer: timer scheduled %v times", diff)
+	}
+	if !root.Active() {
+		t.Errorf("stopped timer: heap root was removed")
+	}
+	checkHeap(t, s)
+	timer.Stop()
+}
+
 func TestStop(t *testing.T) {
 	timer := NewTimer(time.Second)
 	wasActive := timer.Stop()
@@ -260,7 +308,7 @@ func TestMultipleZeroResets(t *testing.T) {
 
 func TestResetChannelClear(t *testing.T) {
 	timer := NewTimer(0)
-	time.Sleep(time.Second)
+	Flush()
 
 	if len(timer.C) != 1 {
 		t.Errorf("reset timer: channel should be filled")
@@ -283,6 +331,64 @@ func TestResetChannelClear(t *testing.T) {
 	}
 }
 
+func TestResetKeepPending(t *testing.T) {
+	timer := NewTimer(0)
+	time.Sleep(100 * time.Millisecond)
+
+	if timer.ResetKeepPending(time.Hour) {
+		t.Errorf("reset keep pending: was active is true")
+	}
+	if len(timer.C) != 1 {
+		t.Fatalf("reset keep pending: pending value was cleared")
+	}
+	<-timer.C
+
+	if !timer.ResetKeepPending(50 * time.Millisecond) {
+		t.Errorf("reset keep pending: was active is false")
+	}
+	select {
+	case <-timer.C:
+	case <-time.After(time.Second):
+		t.Errorf("reset keep pending: timer did not fire")
+	}
+}
+
+func TestExpireNow(t *testing.T) {
+	timer := NewTimer(0)
+	time.Sleep(100 * time.Millisecond)
+	old := <-timer.C
+	timer.Reset(0)
+	time.Sleep(100 * time.Millisecond)
+
+	timer.Reset(time.Hour)
+	start := time.Now()
+	if !timer.ExpireNow() {
+		t.Errorf("expire now: was active is false")
+	}
+	if timer.Active() {
+		t.Errorf("expire now: timer is still active")
+	}
+	select {
+	case v := <-timer.C:
+		if v.Before(start) || !v.After(old) {
+			t.Errorf("expire now: stale value %v", v)
+		}
+	default:
+		t.Fatalf("expire now: timer did not fire")
+	}
+
+	// A pending value is replaced.
+	timer.Reset(0)
+	time.Sleep(100 * time.Millisecond)
+	start = time.Now()
+	if timer.ExpireNow() {
+		t.Errorf("expire now: was active is true")
+	}
+	if v := <-timer.C; v.Before(start) {
+		t.Errorf("expire now: pending value was not cleared")
+	}
+}
+
 func TestResetPanic(t *testing.T) {
 	defer func() {
 		r := recover()
@@ -295,6 +401,24 @@ func TestResetPanic(t *testing.T) {
 	timer.Reset(0)
 }
 
+func TestResetErr(t *testing.T) {
+	timer := &Timer{}
+	if err := timer.ResetErr(0); err != ErrUninitialized {
+		t.Errorf("reset err: invalid error %v", err)
+	}
+	if b, err := timer.StopErr(); b || err != ErrUninitialized {
+		t.Errorf("stop err: invalid result %v, %v", b, err)
+	}
+
+	timer = NewStoppedTimer()
+	if err := timer.ResetErr(time.Hour); err != nil {
+		t.Errorf("reset err: %v", err)
+	}
+	if b, err := timer.StopErr(); !b || err != nil {
+		t.Errorf("stop err: invalid result %v, %v", b, err)
+	}
+}
+
 func TestResetBehavior(t *testing.T) {
 	start := time.Now()
 
@@ -308,7 +432,9 @@ func TestResetBehavior(t *testing.T) {
 	// Reset the timer. This should act exactly as creating a new timer.
 	timer.Reset(1 * time.Second)
 
-	// However this will fire immediately, because the channel was not drained.
+	// Reset drained the stale value of the first fire, hence this receives
+	// the new fire after another second. With the standard library's Timer
+	// before Go 1.23 it would fire immediately.
 	// See issue: https://github.com/golang/go/issues/11513
 	<-timer.C
 
@@ -352,3 +478,1581 @@ func TestMultipleTimersConcurrentAddRemove(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestAfterFunc(t *testing.T) {
+	start := time.Now()
+	done := make(chan struct{})
+	timer := AfterFunc(time.Second, func() {
+		close(done)
+	})
+	if timer.C != nil {
+		t.Errorf("after func: channel should be nil")
+	}
+
+	<-done
+	if int(time.Since(start).Seconds()) != 1 {
+		t.Errorf("took ~%v seconds, should be ~1 seconds\n", int(time.Since(start).Seconds()))
+	}
+
+	if timer.Stop() {
+		t.Errorf("after func: was active is true")
+	}
+}
+
+func TestAddAbsolute(t *testing.T) {
+	start := time.Now()
+	called := make(chan interface{}, 1)
+	f := func(arg interface{}) {
+		called <- arg
+	}
+
+	timer := AddAbsolute(start.Add(100*time.Millisecond).UnixNano(), f, 42)
+	if timer.C != nil {
+		t.Errorf("add absolute: channel should be nil")
+	}
+	if arg := <-called; arg != 42 {
+		t.Errorf("add absolute: called with %v, should be 42", arg)
+	}
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Errorf("add absolute: called after %v, should be after 100ms", d)
+	}
+
+	// A deadline in the past fires immediately.
+	AddAbsolute(start.Add(-time.Hour).UnixNano(), f, "past")
+	select {
+	case arg := <-called:
+		if arg != "past" {
+			t.Errorf("add absolute: called with %v, should be past", arg)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("add absolute: past deadline not fired")
+	}
+
+	timer = AddAbsolute(time.Now().Add(100*time.Millisecond).UnixNano(), f, nil)
+	if !timer.Stop() {
+		t.Errorf("add absolute: was active is false")
+	}
+	select {
+	case <-called:
+		t.Errorf("add absolute: callback called after stop")
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestAfterFuncStop(t *testing.T) {
+	called := make(chan struct{}, 1)
+	timer := AfterFunc(100*time.Millisecond, func() {
+		called <- struct{}{}
+	})
+	if !timer.Stop() {
+		t.Errorf("after func: was active is false")
+	}
+
+	select {
+	case <-called:
+		t.Errorf("after func: callback called after stop")
+	case <-time.After(time.Second):
+	}
+}
+
+func TestAfterFuncReset(t *testing.T) {
+	start := time.Now()
+	called := make(chan struct{}, 2)
+	timer := AfterFunc(time.Second, func() {
+		called <- struct{}{}
+	})
+	if !timer.Reset(2 * time.Second) {
+		t.Errorf("after func: was active is false")
+	}
+
+	<-called
+	if int(time.Since(start).Seconds()) != 2 {
+		t.Errorf("took ~%v seconds, should be ~2 seconds\n", int(time.Since(start).Seconds()))
+	}
+
+	select {
+	case <-called:
+		t.Errorf("after func: callback called twice")
+	case <-time.After(time.Second):
+	}
+}
+
+func TestResetFunc(t *testing.T) {
+	var old, cur atomic.Int32
+	timer := AfterFunc(20*time.Millisecond, func() { old.Add(1) })
+
+	if !timer.ResetFunc(50*time.Millisecond, func() { cur.Add(1) }) {
+		t.Errorf("reset func: was active is false")
+	}
+	time.Sleep(200 * time.Millisecond)
+	if old.Load() != 0 || cur.Load() != 1 {
+		t.Errorf("reset func: invalid calls: old %v, new %v", old.Load(), cur.Load())
+	}
+
+	if timer.ResetFunc(0, func() { old.Add(1) }) {
+		t.Errorf("reset func: was active is true")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if old.Load() != 1 || cur.Load() != 1 {
+		t.Errorf("reset func: invalid calls: old %v, new %v", old.Load(), cur.Load())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("reset func: no panic for channel timer")
+		}
+	}()
+	NewStoppedTimer().ResetFunc(0, func() {})
+}
+
+func TestCallbackPanicHandler(t *testing.T) {
+	type panicked struct {
+		r     interface{}
+		stack []byte
+	}
+	c := make(chan panicked, 1)
+	SetCallbackPanicHandler(func(r interface{}, stack []byte) {
+		c <- panicked{r, stack}
+	})
+	defer SetCallbackPanicHandler(nil)
+
+	AfterFunc(0, func() { panic("boom") })
+	select {
+	case p := <-c:
+		if p.r != "boom" || len(p.stack) == 0 {
+			t.Errorf("callback panic: invalid recovered panic %v", p.r)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("callback panic: handler was not called")
+	}
+
+	// Later timers still fire.
+	done := make(chan struct{})
+	AfterFunc(10*time.Millisecond, func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("callback panic: later timer did not fire")
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	timer := NewTimer(2 * time.Second)
+	if r := timer.Remaining(); r <= time.Second || r > 2*time.Second {
+		t.Errorf("remaining: invalid duration %v", r)
+	}
+	if d := time.Until(timer.Deadline()); d <= time.Second || d > 2*time.Second {
+		t.Errorf("deadline: invalid time %v", d)
+	}
+
+	timer.Stop()
+	if r := timer.Remaining(); r != 0 {
+		t.Errorf("remaining: stopped timer should return 0, got %v", r)
+	}
+	if !timer.Deadline().IsZero() {
+		t.Errorf("deadline: stopped timer should return zero time")
+	}
+
+	timer.Reset(0)
+	<-timer.C
+	if r := timer.Remaining(); r != 0 {
+		t.Errorf("remaining: fired timer should return 0, got %v", r)
+	}
+
+	if r := NewStoppedTimer().Remaining(); r != 0 {
+		t.Errorf("remaining: new stopped timer should return 0, got %v", r)
+	}
+}
+
+func TestStopAndDrain(t *testing.T) {
+	timer := NewTimer(time.Second)
+	if !timer.StopAndDrain() {
+		t.Errorf("stop and drain: was active is false")
+	}
+
+	timer.Reset(0)
+	time.Sleep(100 * time.Millisecond)
+	if len(timer.C) != 1 {
+		t.Errorf("stop and drain: channel should be filled")
+	}
+
+	if timer.StopAndDrain() {
+		t.Errorf("stop and drain: was active is true")
+	}
+	if len(timer.C) != 0 {
+		t.Errorf("stop and drain: channel should be empty")
+	}
+
+	// Must not block on an empty channel.
+	if timer.StopAndDrain() {
+		t.Errorf("stop and drain: was active is true")
+	}
+}
+
+func TestNewTimerAt(t *testing.T) {
+	start := time.Now()
+	timer := NewTimerAt(start.Add(time.Second))
+	<-timer.C
+	if int(time.Since(start).Seconds()) != 1 {
+		t.Errorf("took ~%v seconds, should be ~1 seconds\n", int(time.Since(start).Seconds()))
+	}
+
+	// A time in the past fires immediately.
+	start = time.Now()
+	timer = NewTimerAt(start.Add(-time.Hour))
+	<-timer.C
+	if int(time.Since(start).Seconds()) != 0 {
+		t.Errorf("took ~%v seconds, should be ~0 seconds\n", int(time.Since(start).Seconds()))
+	}
+}
+
+func TestResetAt(t *testing.T) {
+	start := time.Now()
+	timer := NewTimer(time.Second)
+	wasActive := timer.ResetAt(start.Add(2 * time.Second))
+	if !wasActive {
+		t.Errorf("reset at: was active is false")
+	}
+
+	<-timer.C
+	if int(time.Since(start).Seconds()) != 2 {
+		t.Errorf("took ~%v seconds, should be ~2 seconds\n", int(time.Since(start).Seconds()))
+	}
+
+	// The wall clock only deadline must be converted as well.
+	start = time.Now()
+	wasActive = timer.ResetAt(start.Round(0).Add(time.Second))
+	if wasActive {
+		t.Errorf("reset at: was active is true")
+	}
+
+	<-timer.C
+	if int(time.Since(start).Seconds()) != 1 {
+		t.Errorf("took ~%v seconds, should be ~1 seconds\n", int(time.Since(start).Seconds()))
+	}
+}
+
+func TestActive(t *testing.T) {
+	timer := NewStoppedTimer()
+	if timer.Active() {
+		t.Errorf("active: stopped timer is active")
+	}
+
+	timer.Reset(100 * time.Millisecond)
+	if !timer.Active() {
+		t.Errorf("active: timer is not active")
+	}
+
+	// Expired but not yet received is inactive.
+	time.Sleep(200 * time.Millisecond)
+	if timer.Active() {
+		t.Errorf("active: expired timer is active")
+	}
+	<-timer.C
+
+	timer.Reset(time.Second)
+	timer.Stop()
+	if timer.Active() {
+		t.Errorf("active: stopped timer is active")
+	}
+}
+
+func TestNewTimers(t *testing.T) {
+	start := time.Now()
+	ds := make([]time.Duration, 1000)
+	for i := range ds {
+		ds[i] = time.Duration(i%3) * time.Second
+	}
+
+	timers := NewTimers(ds)
+	if len(timers) != len(ds) {
+		t.Fatalf("new timers: invalid count %v", len(timers))
+	}
+
+	// The timers must be independent.
+	for i := 0; i < len(timers); i += 2 {
+		if !timers[i].Stop() {
+			t.Errorf("new timers: was active is false")
+		}
+	}
+
+	for i := 1; i < len(timers); i += 2 {
+		<-timers[i].C
+	}
+	if int(time.Since(start).Seconds()) != 2 {
+		t.Errorf("took ~%v seconds, should be ~2 seconds\n", int(time.Since(start).Seconds()))
+	}
+}
+
+func BenchmarkNewTimer(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		timers := make([]*Timer, 100000)
+		for j := range timers {
+			timers[j] = NewTimer(time.Hour)
+		}
+
+		b.StopTimer()
+		for _, timer := range timers {
+			timer.Stop()
+		}
+		b.StartTimer()
+	}
+}
+
+func BenchmarkNewTimers(b *testing.B) {
+	ds := make([]time.Duration, 100000)
+	for i := range ds {
+		ds[i] = time.Hour
+	}
+
+	for i := 0; i < b.N; i++ {
+		timers := NewTimers(ds)
+
+		b.StopTimer()
+		for _, timer := range timers {
+			timer.Stop()
+		}
+		b.StartTimer()
+	}
+}
+
+func TestResetRemaining(t *testing.T) {
+	timer := NewTimer(2 * time.Second)
+	prev, wasActive := timer.ResetRemaining(time.Second)
+	if !wasActive {
+		t.Errorf("reset remaining: was active is false")
+	}
+	if prev <= time.Second || prev > 2*time.Second {
+		t.Errorf("reset remaining: invalid previous duration %v", prev)
+	}
+
+	<-timer.C
+	prev, wasActive = timer.ResetRemaining(time.Second)
+	if wasActive {
+		t.Errorf("reset remaining: was active is true")
+	}
+	if prev != 0 {
+		t.Errorf("reset remaining: fired timer should return 0, got %v", prev)
+	}
+	timer.Stop()
+}
+
+func TestIsStopped(t *testing.T) {
+	timer := NewStoppedTimer()
+	if !timer.IsStopped() {
+		t.Errorf("is stopped: new stopped timer is not stopped")
+	}
+
+	timer.Reset(100 * time.Millisecond)
+	if timer.IsStopped() {
+		t.Errorf("is stopped: active timer is stopped")
+	}
+
+	// Fired and undrained is not stopped.
+	time.Sleep(200 * time.Millisecond)
+	if timer.IsStopped() {
+		t.Errorf("is stopped: undrained timer is stopped")
+	}
+
+	<-timer.C
+	if !timer.IsStopped() {
+		t.Errorf("is stopped: drained timer is not stopped")
+	}
+
+	timer.Reset(time.Second)
+	timer.Stop()
+	if !timer.IsStopped() {
+		t.Errorf("is stopped: stopped timer is not stopped")
+	}
+}
+
+func TestActiveTimers(t *testing.T) {
+	var timers []*Timer
+	for i := 0; i < 100; i++ {
+		timers = append(timers, NewTimer(time.Hour))
+	}
+	n := ActiveTimers()
+
+	for _, timer := range timers {
+		timer.Stop()
+	}
+	if diff := n - ActiveTimers(); diff != 100 {
+		t.Errorf("active timers: invalid count difference %v", diff)
+	}
+}
+
+func TestPendingCallbacks(t *testing.T) {
+	n := PendingCallbacks()
+	m := ActiveTimers()
+
+	timer := AfterFunc(time.Hour, func() {})
+	other := NewTimer(time.Hour)
+	defer other.Stop()
+
+	if diff := PendingCallbacks() - n; diff != 1 {
+		t.Errorf("pending callbacks: invalid count difference %v", diff)
+	}
+	if diff := ActiveTimers() - m; diff != 2 {
+		t.Errorf("pending callbacks: invalid active timers difference %v", diff)
+	}
+	if !timer.Stop() {
+		t.Errorf("pending callbacks: was active is false")
+	}
+	if diff := PendingCallbacks() - n; diff != 0 {
+		t.Errorf("pending callbacks: invalid count difference %v", diff)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	timer := NewTimer(0)
+	time.Sleep(100 * time.Millisecond)
+
+	if timer.Stop() {
+		t.Errorf("drain: was active is true")
+	}
+	if !timer.Drain() {
+		t.Errorf("drain: no value was removed")
+	}
+	if len(timer.C) != 0 {
+		t.Errorf("drain: channel should be empty")
+	}
+
+	// Must not block on a drained timer.
+	if timer.Drain() {
+		t.Errorf("drain: value was removed from empty channel")
+	}
+}
+
+func TestAfter(t *testing.T) {
+	start := time.Now()
+	select {
+	case <-After(time.Second):
+	case <-time.After(2 * time.Second):
+		t.Errorf("after: timer did not fire")
+	}
+	if int(time.Since(start).Seconds()) != 1 {
+		t.Errorf("took ~%v seconds, should be ~1 seconds\n", int(time.Since(start).Seconds()))
+	}
+}
+
+func TestAfterFuncResetInCallback(t *testing.T) {
+	var timer *Timer
+	calls := make(chan int, 10)
+	n := 0
+	timer = AfterFunc(time.Hour, func() {
+		n++
+		calls <- n
+		if n < 3 {
+			timer.Reset(50 * time.Millisecond)
+		} else if timer.Stop() {
+			t.Errorf("after func: stop in callback: was active is true")
+		}
+	})
+	timer.Reset(50 * time.Millisecond)
+
+	for i := 1; i <= 3; i++ {
+		select {
+		case c := <-calls:
+			if c != i {
+				t.Errorf("after func: invalid call %v", c)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("after func: callback was not rescheduled")
+		}
+	}
+
+	select {
+	case <-calls:
+		t.Errorf("after func: callback called after stop")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestAfterFuncResetScheduledOnce(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		var timer *Timer
+		var calls atomic.Int32
+		timer = AfterFunc(time.Hour, func() {
+			// Only the first call reschedules.
+			if calls.Add(1) == 1 {
+				timer.Reset(50 * time.Millisecond)
+			}
+		})
+		timer.Reset(0)
+
+		// Race a Reset against the fire and the Reset within the callback.
+		time.Sleep(time.Duration(i) * 50 * time.Microsecond)
+		timer.Reset(50 * time.Millisecond)
+
+		// A timer scheduled twice would call the callback three times.
+		time.Sleep(200 * time.Millisecond)
+		if c := calls.Load(); c != 2 {
+			t.Errorf("after func: callback called %v times", c)
+		}
+		if timer.Stop() {
+			t.Errorf("after func: timer is still scheduled")
+		}
+	}
+}
+
+func TestStopAll(t *testing.T) {
+	ds := make([]time.Duration, 1000)
+	for i := range ds {
+		ds[i] = time.Hour
+	}
+	timers := append(NewTimers(ds), NewTimer(0))
+	timers = append(timers, NewStoppedTimer())
+	time.Sleep(100 * time.Millisecond)
+
+	results := StopAll(timers)
+	if len(results) != len(timers) {
+		t.Fatalf("stop all: invalid result count %v", len(results))
+	}
+	for i, wasActive := range results {
+		if wasActive != (i < len(ds)) {
+			t.Errorf("stop all: invalid result for timer %v: %v", i, wasActive)
+		}
+	}
+	for _, timer := range timers {
+		if timer.Active() {
+			t.Fatalf("stop all: timer is active")
+		}
+	}
+
+	// Channels are left as-is.
+	if len(timers[len(ds)].C) != 1 {
+		t.Errorf("stop all: channel should be filled")
+	}
+}
+
+func TestAfterStopped(t *testing.T) {
+	c, stop := AfterStopped(100 * time.Millisecond)
+	if !stop() {
+		t.Errorf("after stopped: was active is false")
+	}
+	select {
+	case <-c:
+		t.Errorf("after stopped: stopped timer fired")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	c, stop = AfterStopped(0)
+	<-c
+	if stop() {
+		t.Errorf("after stopped: was active is true")
+	}
+}
+
+func TestStopStatus(t *testing.T) {
+	timer := NewStoppedTimer()
+	if st := timer.StopStatus(); st != NotActive {
+		t.Errorf("stop status: invalid status for stopped timer: %v", st)
+	}
+
+	timer.Reset(time.Hour)
+	if st := timer.StopStatus(); st != Stopped {
+		t.Errorf("stop status: invalid status for active timer: %v", st)
+	}
+	if st := timer.StopStatus(); st != NotActive {
+		t.Errorf("stop status: invalid status for stopped timer: %v", st)
+	}
+
+	timer.Reset(0)
+	time.Sleep(50 * time.Millisecond)
+	if st := timer.StopStatus(); st != AlreadyFired {
+		t.Errorf("stop status: invalid status for fired timer: %v", st)
+	}
+}
+
+func TestStopStatusRace(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		timer := NewTimer(time.Duration(i%10) * time.Microsecond)
+		time.Sleep(time.Duration(i%7) * time.Microsecond)
+
+		switch st := timer.StopStatus(); st {
+		case Stopped:
+			time.Sleep(50 * time.Microsecond)
+			if len(timer.C) != 0 {
+				t.Fatalf("stop status: stopped timer delivered a value")
+			}
+		case AlreadyFired:
+			if len(timer.C) != 1 {
+				t.Fatalf("stop status: fired timer delivered no value")
+			}
+		default:
+			t.Fatalf("stop status: invalid status: %v", st)
+		}
+	}
+}
+
+func TestSnapshotDeadlines(t *testing.T) {
+	var timers []*Timer
+	for i := 0; i < 100; i++ {
+		timers = append(timers, NewTimer(time.Duration(100-i)*time.Hour))
+	}
+	defer StopAll(timers)
+
+	ws := SnapshotDeadlines(ActiveTimers() + 100)
+	if !slices.IsSortedFunc(ws, time.Time.Compare) {
+		t.Fatalf("snapshot deadlines: not in ascending order")
+	}
+	for _, timer := range timers {
+		if !slices.ContainsFunc(ws, timer.Deadline().Equal) {
+			t.Fatalf("snapshot deadlines: missing deadline %v", timer.Deadline())
+		}
+	}
+
+	if ws := SnapshotDeadlines(10); len(ws) != 10 {
+		t.Errorf("snapshot deadlines: invalid length %v", len(ws))
+	}
+	if ws := SnapshotDeadlines(0); len(ws) != 0 {
+		t.Errorf("snapshot deadlines: invalid length %v", len(ws))
+	}
+}
+
+func TestStopWithReason(t *testing.T) {
+	errCancel := errors.New("cancelled")
+
+	timer := NewTimer(time.Hour)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		timer.StopWithReason(errCancel)
+	}()
+	if v := <-timer.C; !v.IsZero() {
+		t.Errorf("stop with reason: received fire instead of cancellation")
+	}
+	if err := timer.StopReason(); err != errCancel {
+		t.Errorf("stop with reason: invalid reason %v", err)
+	}
+
+	// A fired timer does not deliver a cancellation.
+	timer.Reset(0)
+	time.Sleep(50 * time.Millisecond)
+	if timer.StopWithReason(errCancel) {
+		t.Errorf("stop with reason: was active is true")
+	}
+	if v := <-timer.C; v.IsZero() {
+		t.Errorf("stop with reason: fire was replaced by the cancellation")
+	}
+	if len(timer.C) != 0 {
+		t.Errorf("stop with reason: fire and cancellation were delivered")
+	}
+	if err := timer.StopReason(); err != nil {
+		t.Errorf("stop with reason: reason of a fired timer: %v", err)
+	}
+}
+
+func TestMaxTimers(t *testing.T) {
+	n := ActiveTimers()
+	SetMaxTimers(n + 10)
+	defer SetMaxTimers(0)
+
+	var timers []*Timer
+	for i := 0; i < 10; i++ {
+		timer, err := NewTimerErr(time.Hour)
+		if err != nil {
+			t.Fatalf("max timers: %v", err)
+		}
+		timers = append(timers, timer)
+	}
+	defer StopAll(timers)
+
+	if _, err := NewTimerErr(time.Hour); err != ErrTooManyTimers {
+		t.Fatalf("max timers: invalid error %v", err)
+	}
+
+	// Stopped and fired timers are not counted.
+	timers[0].Stop()
+	timers[1].Reset(0)
+	<-timers[1].C
+	for i := 0; i < 2; i++ {
+		timer, err := NewTimerErr(time.Hour)
+		if err != nil {
+			t.Fatalf("max timers: %v", err)
+		}
+		timers = append(timers, timer)
+	}
+	if _, err := NewTimerErr(time.Hour); err != ErrTooManyTimers {
+		t.Fatalf("max timers: invalid error %v", err)
+	}
+}
+
+func TestWaitAny(t *testing.T) {
+	overall := NewTimer(time.Hour)
+	connect := NewTimer(20 * time.Millisecond)
+
+	i, v := WaitAny(overall, connect)
+	if i != 1 || v.IsZero() {
+		t.Errorf("wait any: invalid winner %v at %v", i, v)
+	}
+	if overall.Active() || len(overall.C) != 0 {
+		t.Errorf("wait any: loser was not stopped")
+	}
+
+	// Pending values win and the lowest index wins.
+	a, b := NewTimer(0), NewTimer(0)
+	time.Sleep(50 * time.Millisecond)
+	if i, _ := WaitAny(a, b); i != 0 {
+		t.Errorf("wait any: index %v won over a lower index", i)
+	}
+	if len(b.C) != 0 {
+		t.Errorf("wait any: loser was not drained")
+	}
+}
+
+func TestNextDeadline(t *testing.T) {
+	near := NewTimer(time.Hour)
+	defer near.Stop()
+	far := NewTimer(1000 * time.Hour)
+	defer far.Stop()
+
+	next, ok := NextDeadline()
+	if !ok || next.After(near.Deadline()) {
+		t.Errorf("next deadline: invalid deadline %v, %v", next, ok)
+	}
+	last, ok := FarthestDeadline()
+	if !ok || last.Before(far.Deadline()) {
+		t.Errorf("farthest deadline: invalid deadline %v, %v", last, ok)
+	}
+
+	// Both agree with the snapshot of all deadlines.
+	ws := SnapshotDeadlines(ActiveTimers() + 100)
+	if !next.Equal(ws[0]) || !last.Equal(ws[len(ws)-1]) {
+		t.Errorf("deadlines: %v and %v do not match the snapshot %v and %v", next, last, ws[0], ws[len(ws)-1])
+	}
+}
+
+func TestResetChan(t *testing.T) {
+	timer := NewTimer(0)
+	time.Sleep(10 * time.Millisecond)
+
+	old := timer.C
+	c := make(chan time.Time, 1)
+	if timer.ResetChan(10*time.Millisecond, c) {
+		t.Errorf("reset chan: expired timer was active")
+	}
+	if timer.C != c {
+		t.Errorf("reset chan: C was not switched")
+	}
+	if len(old) != 0 {
+		t.Errorf("reset chan: old channel was not drained")
+	}
+	select {
+	case <-c:
+	case <-time.After(time.Second):
+		t.Fatalf("reset chan: timer did not fire on the new channel")
+	}
+
+	// A closed channel of a timer created with WithCloseOnStop is replaced.
+	timer = NewTimerWithOptions(time.Hour, WithCloseOnStop())
+	timer.Stop()
+	timer.ResetChan(0, c)
+	select {
+	case v := <-c:
+		if v.IsZero() {
+			t.Errorf("reset chan: received zero time after WithCloseOnStop")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("reset chan: timer did not fire after WithCloseOnStop")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("reset chan: AfterFunc timer did not panic")
+		}
+	}()
+	AfterFunc(time.Hour, func() {}).ResetChan(time.Second, c)
+}
+
+func TestResetChanConcurrentFire(t *testing.T) {
+	cs := []chan time.Time{make(chan time.Time, 1), make(chan time.Time, 1)}
+	timer := NewStoppedTimer()
+	for i := 0; i < 1000; i++ {
+		c := cs[i%2]
+		timer.ResetChan(time.Duration(i%3)*time.Microsecond, c)
+		<-c
+		if len(cs[(i+1)%2]) != 0 {
+			t.Fatalf("reset chan: fire was delivered to the old channel after the switch")
+		}
+	}
+}
+
+func TestTouch(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithClock(clk, time.Second)
+
+	clk.Advance(900 * time.Millisecond)
+	if !timer.Touch() {
+		t.Errorf("touch: active timer was not active")
+	}
+	clk.Advance(900 * time.Millisecond)
+	if timer.Fired() {
+		t.Fatalf("touch: timer fired before the touched deadline")
+	}
+	clk.Advance(100 * time.Millisecond)
+	if !timer.Fired() {
+		t.Fatalf("touch: timer did not fire at the touched deadline")
+	}
+
+	// Touch drains the channel and uses the last reset duration.
+	timer.Reset(2 * time.Second)
+	clk.Advance(2 * time.Second)
+	if timer.Touch() || len(timer.C) != 0 {
+		t.Errorf("touch: expired timer was active or not drained")
+	}
+	if r := timer.Remaining(); r != 2*time.Second {
+		t.Errorf("touch: invalid remaining duration %v", r)
+	}
+
+	// Absolute times do not change the duration.
+	timer.ResetAt(clk.Now().Add(time.Hour))
+	timer.Touch()
+	if r := timer.Remaining(); r != 2*time.Second {
+		t.Errorf("touch: invalid remaining duration %v after ResetAt", r)
+	}
+
+	if r := timer.Clone().Remaining(); r != 2*time.Second {
+		t.Errorf("touch: clone has invalid remaining duration %v", r)
+	}
+
+	// A timer first scheduled at an absolute time uses the duration until it.
+	first := newStoppedTimer(options{clock: clk})
+	first.ResetAt(clk.Now().Add(3 * time.Second))
+	clk.Advance(time.Second)
+	first.Touch()
+	if r := first.Remaining(); r != 3*time.Second {
+		t.Errorf("touch: invalid remaining duration %v after first ResetAt", r)
+	}
+	first.Stop()
+}
+
+func TestTouchAbsolute(t *testing.T) {
+	timer := NewTimerAt(time.Now().Add(time.Hour))
+	defer timer.Stop()
+	timer.Touch()
+	if r := timer.Remaining(); r < 59*time.Minute {
+		t.Errorf("touch: invalid remaining duration %v of NewTimerAt", r)
+	}
+
+	called := make(chan struct{}, 1)
+	timer = AddAbsolute(time.Now().Add(time.Hour).UnixNano(), func(interface{}) {
+		called <- struct{}{}
+	}, nil)
+	defer timer.Stop()
+	timer.Touch()
+	if r := timer.Remaining(); r < 59*time.Minute {
+		t.Errorf("touch: invalid remaining duration %v of AddAbsolute", r)
+	}
+	select {
+	case <-called:
+		t.Errorf("touch: AddAbsolute timer fired")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func BenchmarkTouch(b *testing.B) {
+	timer := NewTimer(time.Hour)
+	defer timer.Stop()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		timer.Touch()
+	}
+}
+
+func BenchmarkTouchReset(b *testing.B) {
+	timer := NewTimer(time.Hour)
+	defer timer.Stop()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		timer.Reset(time.Hour)
+	}
+}
+
+func TestResetAndWait(t *testing.T) {
+	timer := NewTimer(0)
+	time.Sleep(10 * time.Millisecond)
+
+	// The stale fire is not returned.
+	start := time.Now()
+	if v := timer.ResetAndWait(20 * time.Millisecond); v.Before(start.Add(20 * time.Millisecond)) {
+		t.Errorf("reset and wait: returned stale or early fire %v", v.Sub(start))
+	}
+
+	for _, d := range []time.Duration{0, -time.Second, math.MinInt64} {
+		done := make(chan struct{})
+		go func() {
+			timer.ResetAndWait(d)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("reset and wait: duration %v did not return promptly", d)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("reset and wait: AfterFunc timer did not panic")
+		}
+	}()
+	AfterFunc(time.Hour, func() {}).ResetAndWait(0)
+}
+
+func TestTryReceive(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithClock(clk, time.Second)
+	if v, ok := timer.TryReceive(); ok || !v.IsZero() {
+		t.Errorf("try receive: received %v before the fire", v)
+	}
+
+	clk.Advance(time.Second)
+	if v, ok := timer.TryReceive(); !ok || !v.Equal(clk.Now()) {
+		t.Errorf("try receive: invalid fire %v, %v", v, ok)
+	}
+	if _, ok := timer.TryReceive(); ok {
+		t.Errorf("try receive: value received twice")
+	}
+	if !timer.Fired() {
+		t.Errorf("try receive: fired state was cleared")
+	}
+
+	timer = NewTimerWithOptions(time.Hour, WithCloseOnStop())
+	timer.Stop()
+	if _, ok := timer.TryReceive(); ok {
+		t.Errorf("try receive: received from closed channel")
+	}
+}
+
+func TestResetChanFor(t *testing.T) {
+	timer := NewTimer(0)
+	time.Sleep(10 * time.Millisecond)
+
+	old := timer.C
+	start := time.Now()
+	c := timer.ResetChanFor(10 * time.Millisecond)
+	if timer.C != c || len(old) != 0 {
+		t.Errorf("reset chan for: channel not switched or old channel not drained")
+	}
+	select {
+	case v := <-c:
+		if v.Before(start) {
+			t.Errorf("reset chan for: received stale fire")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("reset chan for: timer did not fire")
+	}
+
+	// Each call returns a fresh channel.
+	if timer.ResetChanFor(time.Hour) == c {
+		t.Errorf("reset chan for: channel was reused")
+	}
+	timer.Stop()
+}
+
+func TestTotalStats(t *testing.T) {
+	ResetStats()
+	clk := NewFakeClock(time.Unix(0, 0))
+	a := NewTimerWithClock(clk, time.Second)
+	b := NewTimerWithClock(clk, time.Hour)
+	c := NewTimerWithClock(clk, time.Hour)
+	NewStoppedTimer()
+	a.ResetPeriodic(time.Second)
+
+	clk.Advance(3 * time.Second)
+	b.Stop()
+	b.Stop()
+	c.StopAndDrain()
+	a.Stop()
+
+	// Timers of other tests might be counted too.
+	if n := TotalCreated(); n < 3 {
+		t.Errorf("stats: %d timers created, expected at least 3", n)
+	}
+	if n := TotalFired(); n < 3 {
+		t.Errorf("stats: %d fires, expected at least 3", n)
+	}
+	if n := TotalStopped(); n < 3 {
+		t.Errorf("stats: %d timers stopped, expected at least 3", n)
+	}
+
+	ResetStats()
+	if TotalCreated() > 3 || TotalFired() > 3 || TotalStopped() > 3 {
+		t.Errorf("stats: counters were not reset")
+	}
+}
+
+func TestResetDuringFire(t *testing.T) {
+	timer := NewStoppedTimer()
+	for i := 0; i < 10000; i++ {
+		// Let the fire race with the Reset below.
+		timer.Reset(time.Duration(i%3) * time.Microsecond)
+		if i%2 == 0 {
+			runtime.Gosched()
+		}
+		timer.Reset(time.Hour)
+		select {
+		case <-timer.C:
+			t.Fatalf("reset during fire: value from before the reset after %d resets", i)
+		default:
+		}
+	}
+	timer.Stop()
+}
+
+func TestFlush(t *testing.T) {
+	timers := make([]*Timer, 1000)
+	for i := range timers {
+		timers[i] = NewTimer(0)
+	}
+	later := NewTimer(time.Hour)
+	defer later.Stop()
+
+	Flush()
+	for i, timer := range timers {
+		if len(timer.C) != 1 {
+			t.Fatalf("flush: expired timer %d was not fired", i)
+		}
+	}
+	if !later.Active() {
+		t.Errorf("flush: future timer was fired")
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithClock(clk, 2*time.Second)
+
+	clk.Advance(time.Second)
+	if !timer.Pause() {
+		t.Errorf("pause: was active is false")
+	}
+	if timer.Pause() {
+		t.Errorf("pause: paused timer was paused again")
+	}
+	if timer.IsStopped() {
+		t.Errorf("pause: paused timer is stopped")
+	}
+
+	clk.Advance(time.Hour)
+	if len(timer.C) != 0 {
+		t.Fatalf("pause: paused timer fired")
+	}
+
+	if !timer.Resume() {
+		t.Errorf("resume: was paused is false")
+	}
+	if timer.Resume() {
+		t.Errorf("resume: resumed timer was resumed again")
+	}
+	if r := timer.Remaining(); r != time.Second {
+		t.Errorf("resume: invalid remaining duration %v", r)
+	}
+
+	clk.Advance(time.Second)
+	if len(timer.C) != 1 {
+		t.Fatalf("resume: timer did not fire")
+	}
+
+	// Pause of a fired timer is a no-op.
+	if timer.Pause() {
+		t.Errorf("pause: fired timer was paused")
+	}
+
+	// Stop discards the paused state.
+	timer.Reset(time.Second)
+	timer.Pause()
+	timer.Stop()
+	if !timer.IsStopped() {
+		t.Errorf("pause: stopped timer is not stopped")
+	}
+	if timer.Resume() {
+		t.Errorf("resume: stopped timer was resumed")
+	}
+}
+
+func TestString(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithClock(clk, 1200*time.Millisecond)
+	if s := timer.String(); s != "Timer(active, fires in 1.2s)" {
+		t.Errorf("string: invalid active state: %v", s)
+	}
+
+	clk.Advance(200 * time.Millisecond)
+	timer.Pause()
+	if s := timer.String(); s != "Timer(paused, 1s remaining)" {
+		t.Errorf("string: invalid paused state: %v", s)
+	}
+
+	timer.Resume()
+	clk.Advance(time.Second)
+	if s := timer.String(); s != "Timer(fired, undrained)" {
+		t.Errorf("string: invalid fired state: %v", s)
+	}
+
+	<-timer.C
+	if s := timer.String(); s != "Timer(stopped)" {
+		t.Errorf("string: invalid stopped state: %v", s)
+	}
+
+	if s := (&Timer{}).String(); s != "Timer(uninitialized)" {
+		t.Errorf("string: invalid uninitialized state: %v", s)
+	}
+}
+
+func TestResetIfActive(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithClock(clk, time.Second)
+
+	if !timer.ResetIfActive(2 * time.Second) {
+		t.Errorf("reset if active: active timer was not reset")
+	}
+	clk.Advance(time.Second)
+	if len(timer.C) != 0 {
+		t.Errorf("reset if active: timer fired early")
+	}
+	clk.Advance(time.Second)
+
+	// A fired timer is not resurrected and the value is kept.
+	if timer.ResetIfActive(time.Second) {
+		t.Errorf("reset if active: fired timer was reset")
+	}
+	if timer.Active() || len(timer.C) != 1 {
+		t.Errorf("reset if active: fired timer was modified")
+	}
+
+	timer.Reset(time.Second)
+	timer.Stop()
+	if timer.ResetIfActive(time.Second) || timer.Active() {
+		t.Errorf("reset if active: stopped timer was reset")
+	}
+}
+
+func TestFired(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithClock(clk, time.Second)
+	if timer.Fired() {
+		t.Errorf("fired: active timer fired")
+	}
+
+	clk.Advance(time.Second)
+	if !timer.Fired() {
+		t.Errorf("fired: timer did not fire")
+	}
+
+	// Receiving does not clear the fired state.
+	<-timer.C
+	if !timer.Fired() {
+		t.Errorf("fired: received timer did not fire")
+	}
+
+	timer.Reset(time.Second)
+	if timer.Fired() {
+		t.Errorf("fired: reset timer fired")
+	}
+	timer.Stop()
+	clk.Advance(time.Hour)
+	if timer.Fired() {
+		t.Errorf("fired: stopped timer fired")
+	}
+}
+
+func TestFiredSticky(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithClock(clk, time.Second)
+	clk.Advance(time.Second)
+
+	if !timer.Drain() {
+		t.Fatalf("fired sticky: no value was drained")
+	}
+	if !timer.Fired() {
+		t.Errorf("fired sticky: drained timer did not fire")
+	}
+	timer.StopAndDrain()
+	if !timer.Fired() {
+		t.Errorf("fired sticky: stopped timer did not fire")
+	}
+
+	// The fire of an unbuffered timer is dropped without a receiver.
+	timer = NewTimerWithOptions(time.Second, WithClock(clk), WithUnbuffered())
+	clk.Advance(time.Second)
+	if !timer.Fired() {
+		t.Errorf("fired sticky: dropped fire is not reported")
+	}
+}
+
+func TestResetPeriodic(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithClock(clk, time.Hour)
+	c := timer.C
+
+	if !timer.ResetPeriodic(time.Second) {
+		t.Errorf("reset periodic: was active is false")
+	}
+	for i := 1; i <= 3; i++ {
+		clk.Advance(time.Second)
+		if v := <-c; !v.Equal(time.Unix(int64(i), 0)) {
+			t.Errorf("reset periodic: invalid time value %v", v)
+		}
+	}
+
+	// A slow receiver does not accumulate fires.
+	clk.Advance(10 * time.Second)
+	if len(c) != 1 {
+		t.Errorf("reset periodic: channel should hold one value")
+	}
+	if r := timer.Remaining(); r != time.Second {
+		t.Errorf("reset periodic: timer is not aligned: %v", r)
+	}
+
+	if !timer.Stop() {
+		t.Errorf("reset periodic: was active is false")
+	}
+	<-c
+	clk.Advance(time.Hour)
+	if len(c) != 0 {
+		t.Errorf("reset periodic: stopped timer fired")
+	}
+}
+
+func TestLatencyRecorder(t *testing.T) {
+	clk := &delayClock{FakeClock: NewFakeClock(time.Unix(0, 0)), delay: 100 * time.Millisecond}
+	timer := NewTimerWithClock(clk, time.Second)
+
+	var scheduled, actual []time.Time
+	SetLatencyRecorder(func(s, a time.Time) {
+		scheduled = append(scheduled, s)
+		actual = append(actual, a)
+	})
+	defer SetLatencyRecorder(nil)
+
+	clk.Advance(2 * time.Second)
+	if len(scheduled) != 1 {
+		t.Fatalf("latency recorder: invalid record count: %v", len(scheduled))
+	}
+	if !scheduled[0].Equal(time.Unix(1, 0)) || actual[0].Sub(scheduled[0]) != 100*time.Millisecond {
+		t.Errorf("latency recorder: invalid record: %v %v", scheduled[0], actual[0])
+	}
+
+	// The actual time is delivered.
+	if v := <-timer.C; !v.Equal(actual[0]) {
+		t.Errorf("latency recorder: invalid time value %v", v)
+	}
+}
+
+func TestFireTimeActual(t *testing.T) {
+	clk := &delayClock{FakeClock: NewFakeClock(time.Unix(0, 0)), delay: 100 * time.Millisecond}
+	timer := NewTimerWithClock(clk, time.Second)
+	scheduled := timer.Deadline()
+
+	clk.Advance(2 * time.Second)
+	select {
+	case v := <-timer.C:
+		if v.Before(scheduled) {
+			t.Errorf("fire time: %v is before the scheduled time %v", v, scheduled)
+		}
+		if v.Equal(scheduled) {
+			t.Errorf("fire time: delayed fire sent the scheduled time")
+		}
+	default:
+		t.Fatalf("fire time: timer did not fire")
+	}
+}
+
+func TestResetAtEffective(t *testing.T) {
+	clk := NewFakeClock(time.Unix(100, 0))
+	timer := NewTimerWithClock(clk, time.Hour)
+
+	at := time.Unix(160, 0)
+	when, wasActive := timer.ResetAtEffective(at)
+	if !wasActive {
+		t.Errorf("reset at effective: was active is false")
+	}
+	if !when.Equal(at) {
+		t.Errorf("reset at effective: invalid fire time %v", when)
+	}
+
+	clk.Advance(time.Minute)
+	if len(timer.C) != 1 {
+		t.Fatalf("reset at effective: timer did not fire")
+	}
+
+	// Past times are clamped to now and fire immediately.
+	when, wasActive = timer.ResetAtEffective(time.Unix(0, 0))
+	if wasActive {
+		t.Errorf("reset at effective: was active is true")
+	}
+	if !when.Equal(clk.Now()) {
+		t.Errorf("reset at effective: past time not clamped: %v", when)
+	}
+	if v := <-timer.C; !v.Equal(clk.Now()) {
+		t.Errorf("reset at effective: stale value %v", v)
+	}
+}
+
+// time.Time.Add does not wrap around: a sum beyond the range of the
+// monotonic clock reading drops the reading and keeps the wall clock,
+// hence the wake up time of near-max durations stays in the future.
+func TestOverflowDuration(t *testing.T) {
+	clk := NewFakeClock(time.Unix(1<<40, 0))
+	timer := NewTimerWithClock(clk, math.MaxInt64)
+
+	clk.Advance(time.Hour)
+	if len(timer.C) != 0 {
+		t.Fatalf("overflow: timer fired immediately")
+	}
+	if r := timer.Remaining(); r <= 0 {
+		t.Errorf("overflow: invalid remaining duration %v", r)
+	}
+
+	timer = NewTimer(math.MaxInt64)
+	defer timer.Stop()
+	timer.Reset(math.MaxInt64 - 1)
+	time.Sleep(50 * time.Millisecond)
+	if len(timer.C) != 0 {
+		t.Fatalf("overflow: timer fired immediately after reset")
+	}
+	if r := timer.Remaining(); r <= 0 {
+		t.Errorf("overflow: invalid remaining duration %v", r)
+	}
+}
+
+func TestElapsed(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithClock(clk, 10*time.Second)
+
+	clk.Advance(4 * time.Second)
+	if e, d := timer.Elapsed(); e != 4*time.Second || d != 10*time.Second {
+		t.Errorf("elapsed: invalid progress %v of %v", e, d)
+	}
+
+	timer.Reset(time.Minute)
+	clk.Advance(time.Second)
+	if e, d := timer.Elapsed(); e != time.Second || d != time.Minute {
+		t.Errorf("elapsed: invalid progress after reset %v of %v", e, d)
+	}
+
+	timer.Stop()
+	if e, d := timer.Elapsed(); e != d || d != time.Minute {
+		t.Errorf("elapsed: invalid progress of stopped timer %v of %v", e, d)
+	}
+
+	timer.ResetPeriodic(10 * time.Second)
+	clk.Advance(13 * time.Second)
+	if e, d := timer.Elapsed(); e != 3*time.Second || d != 10*time.Second {
+		t.Errorf("elapsed: invalid progress of periodic timer %v of %v", e, d)
+	}
+	timer.Stop()
+
+	if e, d := NewStoppedTimer().Elapsed(); e != 0 || d != 0 {
+		t.Errorf("elapsed: invalid progress of new timer %v of %v", e, d)
+	}
+}
+
+func TestClone(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithClock(clk, time.Second)
+
+	clk.Advance(500 * time.Millisecond)
+	clone := timer.Clone()
+	if !clone.Deadline().Equal(timer.Deadline()) {
+		t.Fatalf("clone: invalid deadline %v", clone.Deadline())
+	}
+
+	clk.Advance(500 * time.Millisecond)
+	v1, v2 := <-timer.C, <-clone.C
+	if !v1.Equal(v2) || !v1.Equal(time.Unix(1, 0)) {
+		t.Errorf("clone: timers fired at %v and %v", v1, v2)
+	}
+
+	// The timers are independent.
+	timer.ResetPeriodic(time.Second)
+	clone = timer.Clone()
+	timer.Stop()
+	clk.Advance(2 * time.Second)
+	if len(timer.C) != 0 || len(clone.C) != 1 {
+		t.Errorf("clone: stop of the original affected the clone")
+	}
+	if !clone.Active() {
+		t.Errorf("clone: period was not cloned")
+	}
+	clone.Stop()
+
+	if timer.Clone().Active() {
+		t.Errorf("clone: clone of stopped timer is active")
+	}
+}
+
+func TestCloneSystemClock(t *testing.T) {
+	timer := NewTimer(50 * time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	clone := timer.Clone()
+	if !clone.Deadline().Equal(timer.Deadline()) {
+		t.Fatalf("clone: invalid deadline %v", clone.Deadline())
+	}
+
+	v1, v2 := <-timer.C, <-clone.C
+	if d := v1.Sub(v2); d > 10*time.Millisecond || d < -10*time.Millisecond {
+		t.Errorf("clone: timers fired %v apart", d)
+	}
+}
+
+func TestResetShorterOnly(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithClock(clk, time.Minute)
+
+	if timer.ResetShorterOnly(time.Hour) {
+		t.Errorf("reset shorter only: longer duration was applied")
+	}
+	if !timer.Deadline().Equal(time.Unix(60, 0)) {
+		t.Errorf("reset shorter only: deadline changed to %v", timer.Deadline())
+	}
+
+	if !timer.ResetShorterOnly(time.Second) {
+		t.Errorf("reset shorter only: shorter duration was ignored")
+	}
+	if !timer.Deadline().Equal(time.Unix(1, 0)) {
+		t.Errorf("reset shorter only: invalid deadline %v", timer.Deadline())
+	}
+
+	// A fired timer is not rescheduled and keeps its value.
+	clk.Advance(time.Second)
+	if timer.ResetShorterOnly(0) {
+		t.Errorf("reset shorter only: fired timer was reset")
+	}
+	if len(timer.C) != 1 {
+		t.Errorf("reset shorter only: fired value was drained")
+	}
+}
+
+func TestStopOlderThan(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	old := NewTimerWithClock(clk, 24*time.Hour)
+	reset := NewTimerWithClock(clk, time.Hour)
+
+	clk.Advance(30 * time.Minute)
+	reset.Reset(time.Hour)
+	far := NewTimerWithClock(clk, 365*24*time.Hour)
+
+	clk.Advance(30 * time.Minute)
+	recent := NewTimerWithClock(clk, time.Minute)
+
+	// Timers left behind by other tests on their clocks might be stopped too.
+	if n := StopOlderThan(45 * time.Minute); n < 2 {
+		t.Errorf("stop older than: expected at least 2 stopped timers, got %d", n)
+	}
+	if old.Active() || reset.Active() {
+		t.Errorf("stop older than: old timers are still active")
+	}
+	if !far.Active() || !recent.Active() {
+		t.Errorf("stop older than: recent timers were stopped")
+	}
+
+	// A reset of a stopped timer does not renew its creation time.
+	old.Reset(time.Hour)
+	if n := StopOlderThan(45 * time.Minute); n < 1 || old.Active() {
+		t.Errorf("stop older than: reset renewed the creation time")
+	}
+	far.Stop()
+	recent.Stop()
+}
+
+func TestNonPositiveResetOrder(t *testing.T) {
+	clk := &frozenClock{FakeClock: NewFakeClock(time.Unix(0, 0))}
+	var order []string
+	record := func(name string) Option {
+		return WithOnFire(func(time.Time) {
+			order = append(order, name)
+		})
+	}
+
+	// a expired a second ago, but was not fired yet.
+	a := NewTimerWithOptions(time.Second, WithClock(clk), record("a"))
+	b := NewTimerWithOptions(time.Hour, WithClock(clk), record("b"))
+	clk.Advance(2 * time.Second)
+	if a.Fired() {
+		t.Fatalf("reset order: frozen clock fired a timer")
+	}
+
+	// The next wake up fires both, a first.
+	b.Reset(-time.Hour)
+	if d := b.Deadline(); !d.Equal(clk.Now()) {
+		t.Errorf("reset order: negative reset was not clamped to now: %v", d)
+	}
+	a.s.wake()
+	if !slices.Equal(order, []string{"a", "b"}) {
+		t.Errorf("reset order: negative reset jumped the queue: %v", order)
+	}
+}
+
+func TestZeroAndTinyResets(t *testing.T) {
+	const n = 1000
+	timers := make([]*Timer, n)
+	for i := range timers {
+		timers[i] = NewTimer(time.Hour)
+	}
+	for i, timer := range timers {
+		switch i % 3 {
+		case 0:
+			timer.Reset(0)
+		case 1:
+			timer.Reset(-time.Duration(i))
+		default:
+			timer.Reset(time.Duration(i) * time.Nanosecond)
+		}
+	}
+
+	deadline := time.After(time.Second)
+	for i, timer := range timers {
+		select {
+		case <-timer.C:
+		case <-deadline:
+			t.Fatalf("zero and tiny resets: timer %d starved", i)
+		}
+	}
+}
+
+func TestExtendBy(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithClock(clk, time.Second)
+	for i := 0; i < 3; i++ {
+		clk.Advance(500 * time.Millisecond)
+		if !timer.ExtendBy(time.Second) {
+			t.Fatalf("extend by: active timer was not active")
+		}
+	}
+	checkHeap(t, clockShard(clk))
+	if r := timer.Remaining(); r != 2500*time.Millisecond {
+		t.Errorf("extend by: invalid remaining duration %v", r)
+	}
+	if !timer.ExtendBy(-time.Hour) || len(timer.C) != 1 {
+		t.Errorf("extend by: negative delta did not fire now")
+	}
+	if timer.ExtendBy(time.Second) {
+		t.Errorf("extend by: fired timer was active")
+	}
+
+	// The deadline saturates.
+	timer.Reset(time.Second)
+	timer.ExtendBy(math.MaxInt64)
+	timer.ExtendBy(math.MaxInt64)
+	if r := timer.Remaining(); r != math.MaxInt64 {
+		t.Errorf("extend by: invalid saturated remaining duration %v", r)
+	}
+	timer.Stop()
+}
+
+func TestExtendByAfterFunc(t *testing.T) {
+	start := time.Now()
+	fired := make(chan time.Time, 1)
+	timer := AfterFunc(20*time.Millisecond, func() {
+		fired <- time.Now()
+	})
+	timer.ExtendBy(20 * time.Millisecond)
+	timer.ExtendBy(20 * time.Millisecond)
+	if d := (<-fired).Sub(start); d < 60*time.Millisecond {
+		t.Errorf("extend by: callback fired after %v", d)
+	}
+}
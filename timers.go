@@ -1,109 +1,1283 @@
 package timer
 
 import (
+	"cmp"
+	"math"
+	"runtime"
+	"runtime/debug"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// A shard is an independent timer heap with its own lock and timer routine.
+// Timers are distributed across the shards to reduce lock contention.
+// Each timer stays in the shard it was assigned to for its whole lifetime.
+type shard struct {
+	mutex  sync.Mutex
+	timers []*Timer
+	clock  Clock
+
+	// rescheduleC wakes the timer routine of shards driven by the system clock.
+	rescheduleC chan struct{}
+
+	// stopWake stops the pending wake up of shards driven by a custom clock.
+	stopWake func() bool
+
+	// wheel replaces the heap if the shard uses a timing wheel.
+	wheel *wheel
+
+	// running is true while the timer routine is running.
+	running bool
+
+	// retired is true if new timers are not assigned to the shard anymore.
+	// The timer routine of a retired shard exits as soon as it is empty.
+	retired bool
+
+	// shutdownC requests the timer routine to exit if the shard is empty.
+	// The timer routine reports on it whether it exited.
+	shutdownC chan bool
+
+	// private is true for the unregistered shards of synctest mode,
+	// which are woken by runtime timers of the caller.
+	private bool
+
+	// attached is true while a shard driven by a custom clock holds timers
+	// and is registered, see attachLocked.
+	attached bool
+
+	// seq numbers the schedules of the shard's timers to fire timers with
+	// equal wake up times in the order they were scheduled.
+	seq uint64
+}
+
 var (
-	mutex       sync.Mutex
-	timers      []*Timer
-	rescheduleC = make(chan struct{}, 1)
+	// shards holds the shards new timers are assigned to.
+	shards    atomic.Pointer[[]*shard]
+	nextShard atomic.Uint32
+
+	// registry holds all shards which might hold timers,
+	// including shards which were replaced and still have timers.
+	registryMutex sync.Mutex
+	registry      = make(map[*shard]struct{})
+
+	// coalesceWindow is the duration to which wake up times are rounded up.
+	coalesceWindow atomic.Int64
+
+	// timeScale holds the bits of the time scale factor, zero means 1.
+	// timeScaleMutex serializes changes of the factor.
+	timeScale      atomic.Uint64
+	timeScaleMutex sync.Mutex
+
+	// synctestMode assigns each new timer to a private shard.
+	synctestMode atomic.Bool
+
+	// strictMode enables the detection of misuse.
+	strictMode atomic.Bool
+
+	// maxTimers limits the number of timers for NewTimerErr if greater than zero.
+	maxTimers atomic.Int64
+
+	// totalCreated, totalFired and totalStopped count the timers which
+	// were created, fired and stopped since the last ResetStats.
+	totalCreated atomic.Uint64
+	totalFired   atomic.Uint64
+	totalStopped atomic.Uint64
+
+	// wakeups counts the wake ups of all timer routines.
+	wakeups atomic.Uint64
+
+	// shutdownMutex serializes shutdown requests.
+	shutdownMutex sync.Mutex
+
+	// callbackPanicHandler is called with panics of AfterFunc functions.
+	callbackPanicHandler atomic.Pointer[func(recovered interface{}, stack []byte)]
+
+	// runnerPanicHandler is called with panics recovered while firing timers.
+	runnerPanicHandler atomic.Pointer[func(recovered interface{})]
+
+	// latencyRecorder is called for each fire if set.
+	latencyRecorder atomic.Pointer[func(scheduled, actual time.Time)]
 )
 
 func init() {
-	go timerRoutine()
+	setShards(newShards(runtime.GOMAXPROCS(0)))
+}
+
+// urgentShard returns the shard of urgent timers, which is created
+// on first use. Its timer routine serves no other timers.
+var urgentShard = sync.OnceValue(func() *shard {
+	return newShard(nil)
+})
+
+// Create n shards and start their timer routines.
+func newShards(n int) []*shard {
+	if n < 1 {
+		n = 1
+	}
+	ss := make([]*shard, n)
+	for i := range ss {
+		ss[i] = newShard(nil)
+	}
+	return ss
+}
+
+// Create n shards of the same kind as the shards ss and start their
+// timer routines.
+func newShardsLike(ss []*shard, n int) []*shard {
+	w := ss[0].wheel
+	if w == nil {
+		return newShards(n)
+	}
+	if n < 1 {
+		n = 1
+	}
+	ns := make([]*shard, n)
+	for i := range ns {
+		ns[i] = newShard(newWheel(w.tick, len(w.buckets)))
+	}
+	return ns
+}
+
+// Create a shard driven by the system clock and start its timer routine.
+// The shard uses the timing wheel w instead of a heap if not nil.
+func newShard(w *wheel) *shard {
+	s := &shard{
+		clock:       SystemClock,
+		rescheduleC: make(chan struct{}, 1),
+		wheel:       w,
+	}
+	s.mutex.Lock()
+	s.startLocked()
+	s.mutex.Unlock()
+	return s
+}
+
+// Start the timer routine of the shard. Must be called with the lock held.
+func (s *shard) startLocked() {
+	s.running = true
+	registerShard(s)
+	go s.timerRoutine()
+}
+
+// Replace the shards new timers are assigned to. The replaced shards keep
+// their timers, but their timer routines exit as soon as they are empty.
+func setShards(ss []*shard) {
+	old := shards.Swap(&ss)
+
+	for _, s := range ss {
+		s.mutex.Lock()
+		s.retired = false
+		if !s.running {
+			s.startLocked()
+		}
+		s.mutex.Unlock()
+	}
+	if old == nil {
+		return
+	}
+
+Retire:
+	for _, s := range *old {
+		for _, n := range ss {
+			if s == n {
+				continue Retire
+			}
+		}
+		s.mutex.Lock()
+		s.retired = true
+		s.reschedule()
+		s.mutex.Unlock()
+	}
+}
+
+// Return the shard for a new timer. Shards are assigned round-robin.
+func pickShard() *shard {
+	if synctestMode.Load() {
+		// The shard has no timer routine and is woken by
+		// runtime timers created by the goroutines using it.
+		return &shard{clock: SystemClock, private: true}
+	}
+	ss := *shards.Load()
+	return ss[int(nextShard.Add(1)-1)%len(ss)]
+}
+
+func registerShard(s *shard) {
+	registryMutex.Lock()
+	registry[s] = struct{}{}
+	registryMutex.Unlock()
+}
+
+func unregisterShard(s *shard) {
+	registryMutex.Lock()
+	delete(registry, s)
+	registryMutex.Unlock()
+}
+
+// Return all shards which might hold timers.
+func allShards() []*shard {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	ss := make([]*shard, 0, len(registry))
+	for s := range registry {
+		ss = append(ss, s)
+	}
+	return ss
+}
+
+// Stop the timer routines of all empty shards.
+// It returns true if no timer routine is running anymore.
+func shutdownShards() bool {
+	shutdownMutex.Lock()
+	defer shutdownMutex.Unlock()
+
+	idle := true
+	for _, s := range allShards() {
+		if !s.shutdown() {
+			idle = false
+		}
+	}
+	return idle
+}
+
+// Stop the timer routine if the shard is empty.
+// It returns true if the timer routine is not running anymore.
+func (s *shard) shutdown() bool {
+	s.mutex.Lock()
+	if !s.running {
+		s.mutex.Unlock()
+		return true
+	}
+	if s.lenLocked() > 0 {
+		s.mutex.Unlock()
+		return false
+	}
+	c := make(chan bool, 1)
+	s.shutdownC = c
+	s.reschedule()
+	s.mutex.Unlock()
+	return <-c
+}
+
+// Return the number of timers in all heaps.
+func countTimers() (n int) {
+	for _, s := range allShards() {
+		s.mutex.Lock()
+		n += s.lenLocked()
+		s.mutex.Unlock()
+	}
+	return
+}
+
+// Add delta to the wake up time of all timers in all heaps.
+func shiftTimers(delta time.Duration) {
+	for _, s := range allShards() {
+		s.mutex.Lock()
+		if s.lenLocked() > 0 {
+			if s.wheel != nil {
+				s.wheel.shift(delta)
+			} else {
+				// A uniform shift keeps the heap order intact.
+				for _, t := range s.timers {
+					t.when = t.when.Add(delta)
+				}
+			}
+			s.reschedule()
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// Return the current time scale factor.
+func timeScaleFactor() float64 {
+	if bits := timeScale.Load(); bits != 0 {
+		return math.Float64frombits(bits)
+	}
+	return 1
+}
+
+// Return the real duration of the duration d in scaled time.
+// Negative durations are clamped to zero: the timer expires now, after
+// the timers which expired before, like for a duration of zero.
+func scaled(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	f := timeScaleFactor()
+	if f == 1 {
+		return d
+	}
+	return scaleDuration(d, 1/f)
+}
+
+// Return d multiplied by f, saturated to the range of a Duration.
+func scaleDuration(d time.Duration, f float64) time.Duration {
+	x := float64(d) * f
+	switch {
+	case x >= math.MaxInt64:
+		return math.MaxInt64
+	case x <= math.MinInt64:
+		return math.MinInt64
+	}
+	return time.Duration(x)
+}
+
+// Change the time scale factor to f and rescale the remaining durations
+// and periods of all timers in all heaps.
+func setTimeScale(f float64) {
+	timeScaleMutex.Lock()
+	defer timeScaleMutex.Unlock()
+
+	r := timeScaleFactor() / f
+	timeScale.Store(math.Float64bits(f))
+	if r == 1 {
+		return
+	}
+	for _, s := range allShards() {
+		s.mutex.Lock()
+		if s.lenLocked() > 0 {
+			now := s.clock.Now()
+			rescale := func(t *Timer) {
+				if t.when.After(now) {
+					t.when = now.Add(scaleDuration(t.when.Sub(now), r))
+				}
+				if t.period > 0 {
+					t.period = max(scaleDuration(t.period, r), 1)
+				}
+			}
+			if s.wheel != nil {
+				s.wheel.rehash(rescale)
+			} else {
+				// Rescaling the remaining durations keeps the heap order intact.
+				for _, t := range s.timers {
+					rescale(t)
+				}
+			}
+			s.reschedule()
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// Return the number of timers created by AfterFunc in all heaps.
+func countCallbacks() (n int) {
+	for _, s := range allShards() {
+		s.mutex.Lock()
+		s.eachLocked(func(t *Timer) {
+			if t.afterFunc {
+				n++
+			}
+		})
+		s.mutex.Unlock()
+	}
+	return
+}
+
+// Stop all timers in all heaps which were created before age elapsed.
+// It returns the number of stopped timers.
+func stopTimersOlder(age time.Duration) (n int) {
+	var ts []*Timer
+	for _, s := range allShards() {
+		s.mutex.Lock()
+		cutoff := s.clock.Now().Add(-age)
+		s.eachLocked(func(t *Timer) {
+			if t.created.Before(cutoff) {
+				ts = append(ts, t)
+			}
+		})
+		// Stop after the walk, which must not see the heap change.
+		for _, t := range ts {
+			if s.stopTimerLocked(t) {
+				n++
+			}
+		}
+		ts = ts[:0]
+		s.mutex.Unlock()
+	}
+	return
+}
+
+// Call f for each timer in the shard.
+func (s *shard) eachLocked(f func(t *Timer)) {
+	if s.wheel != nil {
+		for _, b := range s.wheel.buckets {
+			for _, t := range b {
+				f(t)
+			}
+		}
+		return
+	}
+	for _, t := range s.timers {
+		f(t)
+	}
+}
+
+// Return the wake up times of all timers in all heaps in ascending order.
+func timerDeadlines() []time.Time {
+	var ws []time.Time
+	for _, s := range allShards() {
+		s.mutex.Lock()
+		s.eachLocked(func(t *Timer) {
+			ws = append(ws, t.when)
+		})
+		s.mutex.Unlock()
+	}
+	slices.SortFunc(ws, time.Time.Compare)
+	return ws
+}
+
+// Return the earliest or, if last is true, the latest wake up time
+// of the timers in all heaps and false if no timer is scheduled.
+func timerDeadline(last bool) (when time.Time, ok bool) {
+	visit := func(t *Timer) {
+		if !ok || (last && t.when.After(when)) || (!last && t.when.Before(when)) {
+			when, ok = t.when, true
+		}
+	}
+	for _, s := range allShards() {
+		s.mutex.Lock()
+		if s.wheel == nil && !last && len(s.timers) > 0 {
+			// The root of the heap expires first.
+			visit(s.timers[0])
+		} else {
+			s.eachLocked(visit)
+		}
+		s.mutex.Unlock()
+	}
+	return
+}
+
+// Return the state of the timers in all heaps ordered by their wake up
+// times. All shards are locked at once for a consistent cut.
+func snapshotTimers() []TimerState {
+	ss := allShards()
+	for _, s := range ss {
+		s.mutex.Lock()
+	}
+	type entry struct {
+		when time.Time
+		st   TimerState
+	}
+	var es []entry
+	for _, s := range ss {
+		now := s.clock.Now()
+		s.eachLocked(func(t *Timer) {
+			es = append(es, entry{
+				when: t.when,
+				st: TimerState{
+					Remaining: max(t.when.Sub(now), 0),
+					Period:    t.period,
+					Tag:       t.tag,
+					Prio:      t.prio,
+				},
+			})
+		})
+	}
+	for _, s := range ss {
+		s.mutex.Unlock()
+	}
+
+	slices.SortStableFunc(es, func(a, b entry) int {
+		if c := a.when.Compare(b.when); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.st.Prio, b.st.Prio)
+	})
+	states := make([]TimerState, len(es))
+	for i, e := range es {
+		states[i] = e.st
+	}
+	return states
+}
+
+// Fire the expired timers of all heaps in the calling goroutine.
+func flushTimers() {
+	for _, s := range allShards() {
+		s.mutex.Lock()
+		if s.lenLocked() > 0 {
+			if _, ok := s.runTimersLocked(s.clock.Now()); ok {
+				// Let the timer routine sleep until the new root expires.
+				s.reschedule()
+			} else {
+				s.detachLocked()
+			}
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// Return the number of timers in the shard.
+func (s *shard) lenLocked() int {
+	if s.wheel != nil {
+		return s.wheel.n
+	}
+	return len(s.timers)
 }
 
 // Add the timer to the heap.
 func addTimer(t *Timer, d time.Duration) {
-	t.when = time.Now().Add(d)
+	s := t.s
+	s.mutex.Lock()
+	t.dur = d
+	t.when = s.clock.Now().Add(scaled(d))
+	s.addTimerLocked(t)
+	s.mutex.Unlock()
+}
 
-	mutex.Lock()
-	addTimerLocked(t)
-	mutex.Unlock()
+// Add the timer to the heap with the absolute wake up time when.
+func addTimerAt(t *Timer, when time.Time) {
+	s := t.s
+	s.mutex.Lock()
+	t.initDurLocked(when)
+	t.when = when
+	s.addTimerLocked(t)
+	s.mutex.Unlock()
 }
 
-func addTimerLocked(t *Timer) {
-	t.i = len(timers)
-	timers = append(timers, t)
-	siftupTimer(t.i)
+// Add multiple timers to the heap with a single lock acquisition.
+// All timers are moved to the same shard.
+func addTimers(ts []*Timer, ds []time.Duration) {
+	s := pickShard()
+	now := s.clock.Now()
+	for i, t := range ts {
+		t.s = s
+		t.dur = ds[i]
+		t.when = now.Add(scaled(ds[i]))
+	}
+
+	s.mutex.Lock()
+	for _, t := range ts {
+		s.addTimerLocked(t)
+	}
+	s.mutex.Unlock()
+}
+
+func (s *shard) addTimerLocked(t *Timer) {
+	if s.rescheduleC == nil {
+		s.attachLocked()
+	}
+	t.prepare()
+	if t.tag != nil {
+		tagTimer(t)
+	}
+	if s.wheel != nil {
+		if s.wheel.n == 0 {
+			// The wheel was idle, skip all ticks passed in the meantime.
+			s.wheel.cur = s.wheel.tickOf(s.clock.Now())
+			s.reschedule()
+		}
+		s.wheel.add(t)
+		return
+	}
+	s.heapPush(t)
 
 	// Reschedule if this is the next timer in the heap.
 	if t.i == 0 {
-		reschedule()
+		s.reschedule()
 	}
 }
 
+// Reset the state of timer t for a new schedule and adjust its wake up time.
+func (t *Timer) prepare() {
+	t.start = t.s.clock.Now()
+	t.s.seq++
+	t.seq = t.s.seq
+	if t.created.IsZero() {
+		t.created = t.start
+		totalCreated.Add(1)
+	}
+	t.fired = false
+	t.reason = nil
+	if t.done != nil && isClosed(t.done) {
+		t.done = make(chan struct{})
+	}
+	if t.minInterval > 0 && !t.lastFire.IsZero() {
+		if floor := t.lastFire.Add(t.minInterval); t.when.Before(floor) {
+			t.when = floor
+		}
+	}
+	t.when = coalesce(t.when)
+}
+
 // Delete timer t from the heap.
 // It returns true if t was removed, false if t wasn't even there.
 // Do not need to update the timer routine: if it wakes up early, no big deal.
 func delTimer(t *Timer) (b bool) {
-	mutex.Lock()
-	b = delTimerLocked(t)
-	mutex.Unlock()
+	s := t.s
+	s.mutex.Lock()
+	b = s.delTimerLocked(t)
+	s.mutex.Unlock()
 	return
 }
 
 // Delete timer t from the heap.
 // It returns true if t was removed, false if t wasn't even there.
 // Do not need to update the timer routine: if it wakes up early, no big deal.
-func delTimerLocked(t *Timer) bool {
+func (s *shard) delTimerLocked(t *Timer) bool {
+	t.paused = false
+	if t.tag != nil {
+		untagTimer(t)
+	}
+	if s.wheel != nil {
+		return s.wheel.del(t)
+	}
+	if !s.activeTimerLocked(t) {
+		return false
+	}
+	s.heapRemove(t)
+	s.detachLocked()
+	return true
+}
+
+// Delete timer t from the heap and call its stop function.
+// It returns true if t was removed, false if t wasn't even there.
+func stopTimer(t *Timer) (b bool) {
+	s := t.s
+	s.mutex.Lock()
+	b = s.stopTimerLocked(t)
+	s.mutex.Unlock()
+	return
+}
+
+func (s *shard) stopTimerLocked(t *Timer) (b bool) {
+	b = s.delTimerLocked(t)
+	if b {
+		totalStopped.Add(1)
+	}
+	if t.stop != nil {
+		t.stop()
+	}
+	return
+}
+
+// Delete timer t from the heap and call its stop function.
+// It returns the state of t before the call.
+func stopTimerStatus(t *Timer) (st Status) {
+	s := t.s
+	s.mutex.Lock()
+	switch {
+	case s.stopTimerLocked(t):
+		st = Stopped
+	case t.fired:
+		st = AlreadyFired
+	default:
+		st = NotActive
+	}
+	s.mutex.Unlock()
+	return
+}
+
+// Delete timer t from the heap, call its stop function and send the zero
+// time if it was removed. It returns true if t was removed.
+func stopTimerReason(t *Timer, err error) (b bool) {
+	s := t.s
+	s.mutex.Lock()
+	b = s.stopTimerLocked(t)
+	if b {
+		t.reason = err
+		if t.cancel != nil {
+			t.cancel()
+		}
+	}
+	s.mutex.Unlock()
+	return
+}
+
+// Return the done channel of timer t.
+func timerDone(t *Timer) (c chan struct{}) {
+	s := t.s
+	s.mutex.Lock()
+	c = t.done
+	s.mutex.Unlock()
+	return
+}
+
+// Report whether the channel c is closed. c must not receive values.
+func isClosed(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+// Return the reason timer t was stopped for.
+func timerReason(t *Timer) (err error) {
+	s := t.s
+	s.mutex.Lock()
+	err = t.reason
+	s.mutex.Unlock()
+	return
+}
+
+// Stop multiple timers with a single lock acquisition per shard.
+// It returns for each timer whether it was removed.
+func stopTimers(ts []*Timer) []bool {
+	bs := make([]bool, len(ts))
+	indexes := make(map[*shard][]int)
+	for i, t := range ts {
+		indexes[t.s] = append(indexes[t.s], i)
+	}
+	for s, is := range indexes {
+		s.mutex.Lock()
+		for _, i := range is {
+			bs[i] = s.stopTimerLocked(ts[i])
+		}
+		s.mutex.Unlock()
+	}
+	return bs
+}
+
+// Delete timer t from the heap, clear the channel
+// and call its stop function.
+// It returns true if t was removed, false if t wasn't even there.
+func delTimerAndReset(t *Timer) (b bool) {
+	s := t.s
+	s.mutex.Lock()
+	b = s.delTimerLocked(t)
+	if b {
+		totalStopped.Add(1)
+	}
+	t.reset()
+	if t.stop != nil {
+		t.stop()
+	}
+	s.mutex.Unlock()
+	return
+}
+
+// Panic if the strict mode is enabled and the caller of method misuses t.
+func checkStrict(t *Timer, method string) {
+	if !strictMode.Load() {
+		return
+	}
+	s := t.s
+	s.mutex.Lock()
+	pooled := t.pooled
+	s.mutex.Unlock()
+	if pooled {
+		panic("timer: " + method + " called on Timer after PutTimer")
+	}
+}
+
+// Panic if the strict mode is enabled and receiving from t blocks forever.
+func checkStrictReceive(t *Timer, method string) {
+	// The closed channel of a WithCloseOnStop timer does not block.
+	if strictMode.Load() && t.stop == nil && timerStopped(t) {
+		panic("timer: " + method + " called on stopped Timer, which never fires")
+	}
+}
+
+// Mark timer t as returned to the pool.
+func setTimerPooled(t *Timer, pooled bool) {
+	s := t.s
+	s.mutex.Lock()
+	t.pooled = pooled
+	if !pooled {
+		// A timer from the pool counts as newly created.
+		t.created = time.Time{}
+	}
+	s.mutex.Unlock()
+}
+
+// Set the channel signaled after each fire of the timers.
+func setTimersNotify(ts []*Timer, c chan struct{}) {
+	for _, t := range ts {
+		s := t.s
+		s.mutex.Lock()
+		t.notify = c
+		s.mutex.Unlock()
+	}
+}
+
+// Receive a pending value from the channel of timer t without blocking.
+func tryReceiveTimer(t *Timer) (v time.Time, ok bool) {
+	s := t.s
+	s.mutex.Lock()
+	select {
+	case v, ok = <-t.C:
+	default:
+	}
+	s.mutex.Unlock()
+	return
+}
+
+// Clear the channel of timer t.
+// It returns true if a value was removed.
+func drainTimer(t *Timer) (b bool) {
+	s := t.s
+	s.mutex.Lock()
+	b = t.reset()
+	s.mutex.Unlock()
+	return
+}
+
+// Report whether timer t is registered in the heap.
+func (s *shard) activeTimerLocked(t *Timer) bool {
+	if s.wheel != nil {
+		return s.wheel.active(t)
+	}
 	// t may not be registered anymore and may have
 	// a bogus i (typically 0, if generated by Go).
 	// Verify it before proceeding.
 	i := t.i
-	last := len(timers) - 1
-	if i < 0 || i > last || timers[i] != t {
+	return i >= 0 && i < len(s.timers) && s.timers[i] == t
+}
+
+// Report whether timer t is registered in the heap.
+func timerActive(t *Timer) (b bool) {
+	s := t.s
+	if s == nil {
 		return false
 	}
-	if i != last {
-		timers[i] = timers[last]
-		timers[i].i = i
+	s.mutex.Lock()
+	b = s.activeTimerLocked(t)
+	s.mutex.Unlock()
+	return
+}
+
+// Report whether timer t fired since it was scheduled.
+func timerFired(t *Timer) (b bool) {
+	s := t.s
+	if s == nil {
+		return false
 	}
-	timers[last] = nil
-	timers = timers[:last]
-	if i != last {
-		siftupTimer(i)
-		siftdownTimer(i)
+	s.mutex.Lock()
+	b = t.fired
+	s.mutex.Unlock()
+	return
+}
+
+// Report whether timer t is neither registered in the heap, nor paused,
+// nor has a pending value in its channel.
+func timerStopped(t *Timer) (b bool) {
+	s := t.s
+	if s == nil {
+		return true
 	}
-	return true
+	s.mutex.Lock()
+	b = !s.activeTimerLocked(t) && !t.paused && !t.pending()
+	s.mutex.Unlock()
+	return
+}
+
+// Report whether a fire of timer t is pending in C or Sig.
+func (t *Timer) pending() bool {
+	return len(t.C) > 0 || len(t.Sig) > 0
+}
+
+// Return a description of the state of timer t.
+func timerString(t *Timer) string {
+	s := t.s
+	if s == nil {
+		return "Timer(uninitialized)"
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch {
+	case s.activeTimerLocked(t):
+		remaining := t.when.Sub(s.clock.Now())
+		if remaining < 0 {
+			remaining = 0
+		}
+		return "Timer(active, fires in " + remaining.Round(time.Millisecond).String() + ")"
+	case t.paused:
+		return "Timer(paused, " + t.remaining.Round(time.Millisecond).String() + " remaining)"
+	case t.pending():
+		return "Timer(fired, undrained)"
+	default:
+		return "Timer(stopped)"
+	}
+}
+
+// Return the duration since timer t was scheduled and its total duration.
+func timerElapsed(t *Timer) (elapsed, total time.Duration) {
+	s := t.s
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	total = t.when.Sub(t.start)
+	if s.activeTimerLocked(t) {
+		elapsed = s.clock.Now().Sub(t.start)
+		if elapsed > total {
+			elapsed = total
+		}
+	} else {
+		elapsed = total
+	}
+	s.mutex.Unlock()
+	return
+}
+
+// Return the wake up time of timer t.
+// The zero time is returned if t is not registered in the heap.
+func timerWhen(t *Timer) (when time.Time) {
+	s := t.s
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.activeTimerLocked(t) {
+		when = t.when
+	}
+	s.mutex.Unlock()
+	return
 }
 
 // Reset the timer to the new timeout duration.
+// A period greater than zero turns the timer into a periodic timer.
+// This clears the channel.
+func resetTimer(t *Timer, d, period time.Duration) (b bool) {
+	s := t.s
+	s.mutex.Lock()
+	t.dur = d
+	b = s.resetTimerLocked(t, s.clock.Now().Add(scaled(d)), scaled(period))
+	s.mutex.Unlock()
+	return
+}
+
+// Reset the timer to expire after its last duration again,
+// keeping its period.
+func touchTimer(t *Timer) (b bool) {
+	s := t.s
+	s.mutex.Lock()
+	b = s.resetTimerLocked(t, s.clock.Now().Add(scaled(t.dur)), t.period)
+	s.mutex.Unlock()
+	return
+}
+
+// Reset the timer to the new absolute wake up time.
+// A period greater than zero turns the timer into a periodic timer.
 // This clears the channel.
-func resetTimer(t *Timer, d time.Duration) (b bool) {
-	mutex.Lock()
-	b = delTimerLocked(t)
+func resetTimerAt(t *Timer, when time.Time, period time.Duration) (b bool) {
+	s := t.s
+	s.mutex.Lock()
+	t.initDurLocked(when)
+	b = s.resetTimerLocked(t, when, period)
+	s.mutex.Unlock()
+	return
+}
+
+// Set the duration used by Touch of a timer which is scheduled for the
+// first time at the absolute time when to the duration until when.
+// Later absolute schedules keep the duration. Must be called with the
+// lock held.
+func (t *Timer) initDurLocked(when time.Time) {
+	if !t.created.IsZero() {
+		return
+	}
+	d := max(when.Sub(t.s.clock.Now()), 0)
+	if f := timeScaleFactor(); f != 1 {
+		d = scaleDuration(d, f)
+	}
+	t.dur = d
+}
+
+// Reset the timer to the new timeout duration and return the duration
+// which was remaining before the reset.
+// This clears the channel.
+func resetTimerRemaining(t *Timer, d time.Duration) (prev time.Duration, b bool) {
+	s := t.s
+	now := s.clock.Now()
+	s.mutex.Lock()
+	if s.activeTimerLocked(t) {
+		prev = t.when.Sub(now)
+		if prev < 0 {
+			prev = 0
+		}
+	}
+	t.dur = d
+	b = s.resetTimerLocked(t, now.Add(scaled(d)), 0)
+	s.mutex.Unlock()
+	return
+}
+
+// Reset the timer to the new timeout duration, but only if it is
+// registered in the heap. It returns true if the timer was reset.
+func resetActiveTimer(t *Timer, d time.Duration) (b bool) {
+	s := t.s
+	s.mutex.Lock()
+	if s.activeTimerLocked(t) {
+		t.dur = d
+		b = s.resetTimerLocked(t, s.clock.Now().Add(scaled(d)), 0)
+	}
+	s.mutex.Unlock()
+	return
+}
+
+// Reset the timer to the new timeout duration, but only if it is
+// registered in the heap and expires earlier then.
+// It returns true if the timer was reset.
+func resetTimerShorter(t *Timer, d time.Duration) (b bool) {
+	s := t.s
+	s.mutex.Lock()
+	if s.activeTimerLocked(t) {
+		if when := s.clock.Now().Add(scaled(d)); when.Before(t.when) {
+			t.dur = d
+			b = s.resetTimerLocked(t, when, 0)
+		}
+	}
+	s.mutex.Unlock()
+	return
+}
+
+// Clear the channel of timer t and fire it immediately.
+// A periodic timer is rescheduled one period after now.
+// It returns true if the timer had been active.
+func expireTimer(t *Timer) (b bool) {
+	s := t.s
+	s.mutex.Lock()
+	b = s.delTimerLocked(t)
 	t.reset()
-	t.when = time.Now().Add(d)
-	addTimerLocked(t)
-	mutex.Unlock()
+	now := s.clock.Now()
+	fireTimer(t, now)
+	if t.period > 0 {
+		t.when = now.Add(t.period)
+		s.addTimerLocked(t)
+	}
+	s.mutex.Unlock()
+	return
+}
+
+// Reset the timer to call f after duration d.
+func resetTimerFunc(t *Timer, d time.Duration, f func()) (b bool) {
+	s := t.s
+	s.mutex.Lock()
+	b = s.delTimerLocked(t)
+	t.fn = f
+	t.dur = d
+	t.when = s.clock.Now().Add(scaled(d))
+	t.period = 0
+	s.addTimerLocked(t)
+	s.mutex.Unlock()
 	return
 }
 
-func reschedule() {
+// Schedule timer c at the wake up time and with the period of timer t,
+// if t is scheduled.
+func cloneTimer(t, c *Timer) {
+	s := t.s
+	s.mutex.Lock()
+	active := s.activeTimerLocked(t)
+	when, period, dur := t.when, t.period, t.dur
+	s.mutex.Unlock()
+
+	s = c.s
+	s.mutex.Lock()
+	c.dur = dur
+	if active {
+		s.resetTimerLocked(c, when, period)
+	}
+	s.mutex.Unlock()
+}
+
+// Move the wake up time of the active timer t by delta, keeping its state.
+// The remaining duration saturates instead of overflowing and is clamped
+// to zero, like for negative durations.
+func extendTimer(t *Timer, delta time.Duration) (b bool) {
+	s := t.s
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.activeTimerLocked(t) {
+		return false
+	}
+	now := s.clock.Now()
+	d := t.when.Sub(now)
+	switch {
+	case delta > 0 && d > math.MaxInt64-delta:
+		d = math.MaxInt64
+	case delta < 0 && d < math.MinInt64-delta:
+		d = 0
+	default:
+		d = max(d+delta, 0)
+	}
+	when := now.Add(d)
+
+	if s.wheel != nil {
+		s.wheel.del(t)
+		t.when = when
+		s.wheel.add(t)
+		return true
+	}
+	old := t.when
+	t.when = when
+	if when.Before(old) {
+		s.siftupTimer(t.i)
+	} else {
+		s.siftdownTimer(t.i)
+	}
+	if t.i == 0 {
+		s.reschedule()
+	}
+	return true
+}
+
+// Reset the timer to expire after duration d and switch its channel to c.
+// Only the old channel is drained.
+func resetTimerChan(t *Timer, d time.Duration, c chan time.Time) (b bool) {
+	s := t.s
+	s.mutex.Lock()
+	b = s.delTimerLocked(t)
+	t.reset()
+	t.redirect(c)
+	t.C = c
+	t.dur = d
+	t.when = s.clock.Now().Add(scaled(d))
+	t.period = 0
+	s.addTimerLocked(t)
+	s.mutex.Unlock()
+	return
+}
+
+// Reset the timer to the absolute time at, based on the monotonic clock.
+// It returns the effective wake up time, which is not before now.
+// This clears the channel.
+func resetTimerAtEffective(t *Timer, at time.Time) (when time.Time, b bool) {
+	s := t.s
+	s.mutex.Lock()
+	now := s.clock.Now()
+	t.initDurLocked(at)
+	b = s.resetTimerLocked(t, now.Add(at.Sub(now)), 0)
+	when = t.when
+	if when.Before(now) {
+		when = now
+	}
+	s.mutex.Unlock()
+	return
+}
+
+// Reset the timer to expire after duration d without clearing the channel.
+func resetTimerKeepPending(t *Timer, d time.Duration) (b bool) {
+	s := t.s
+	s.mutex.Lock()
+	b = s.delTimerLocked(t)
+	t.dur = d
+	t.when = s.clock.Now().Add(scaled(d))
+	t.period = 0
+	s.addTimerLocked(t)
+	s.mutex.Unlock()
+	return
+}
+
+func (s *shard) resetTimerLocked(t *Timer, when time.Time, period time.Duration) (b bool) {
+	if s.wheel == nil && s.activeTimerLocked(t) {
+		// Update the wake up time in place and restore
+		// the heap order with a single sift.
+		t.reset()
+		old := t.when
+		t.when = when
+		t.period = period
+		t.prepare()
+		if t.when.Before(old) {
+			s.siftupTimer(t.i)
+		} else {
+			s.siftdownTimer(t.i)
+		}
+
+		// Reschedule if this is the next timer in the heap.
+		if t.i == 0 {
+			s.reschedule()
+		}
+		return true
+	}
+
+	b = s.delTimerLocked(t)
+	t.reset()
+	t.when = when
+	t.period = period
+	s.addTimerLocked(t)
+	return
+}
+
+// Remove the timer from the heap and remember its remaining duration.
+// It returns false if the timer was not active.
+func pauseTimer(t *Timer) (b bool) {
+	s := t.s
+	s.mutex.Lock()
+	if s.activeTimerLocked(t) {
+		remaining := t.when.Sub(s.clock.Now())
+		if remaining < 0 {
+			remaining = 0
+		}
+		s.delTimerLocked(t)
+		t.paused = true
+		t.remaining = remaining
+		b = true
+	}
+	s.mutex.Unlock()
+	return
+}
+
+// Add a paused timer with its remaining duration back to the heap.
+// It returns false if the timer was not paused.
+func resumeTimer(t *Timer) (b bool) {
+	s := t.s
+	s.mutex.Lock()
+	if t.paused {
+		t.paused = false
+		t.when = s.clock.Now().Add(t.remaining)
+		s.addTimerLocked(t)
+		b = true
+	}
+	s.mutex.Unlock()
+	return
+}
+
+// Round the wake up time when up to the next multiple of the coalesce window.
+func coalesce(when time.Time) time.Time {
+	w := time.Duration(coalesceWindow.Load())
+	if w <= 0 {
+		return when
+	}
+	// Truncate strips the monotonic clock reading, hence only use the offset.
+	if r := when.Sub(when.Truncate(w)); r > 0 {
+		return when.Add(w - r)
+	}
+	return when
+}
+
+// Convert the absolute time at to a wake up time based on the monotonic clock.
+// Wall clock changes after this call do not affect the returned time.
+func monotonicAt(t *Timer, at time.Time) time.Time {
+	now := t.s.clock.Now()
+	return now.Add(at.Sub(now))
+}
+
+// Reschedule the shard's wake up. Must be called with the lock held.
+func (s *shard) reschedule() {
+	if s.rescheduleC == nil {
+		s.wakeLocked()
+		return
+	}
+
+	// The timer routine of a retired shard is restarted on demand.
+	if !s.running {
+		s.startLocked()
+	}
+
 	// Do not block if there is already a pending reschedule request.
 	select {
-	case rescheduleC <- struct{}{}:
+	case s.rescheduleC <- struct{}{}:
 	default:
 	}
 }
 
-func timerRoutine() {
-	var now time.Time
-	var last int
-
+func (s *shard) timerRoutine() {
 	var sleepTimerActive bool
 	sleepTimer := time.NewTimer(time.Second)
 	sleepTimer.Stop()
 
-Loop:
 	for {
 		select {
 		case <-sleepTimer.C:
 
-		case <-rescheduleC:
+		case <-s.rescheduleC:
 			// If not yet received a value from sleepTimer.C, the timer must be
 			// stopped and—if Stop reports that the timer expired before being
 			// stopped—the channel explicitly drained.
@@ -112,61 +1286,218 @@ Loop:
 			}
 		}
 		sleepTimerActive = false
+		wakeups.Add(1)
 
-	Reschedule:
-		now = time.Now()
+		s.mutex.Lock()
+		delta, ok := s.runTimersLocked(s.clock.Now())
+		exit := !ok && (s.retired || s.shutdownC != nil)
+		if s.shutdownC != nil {
+			s.shutdownC <- exit
+			s.shutdownC = nil
+		}
+		if exit {
+			s.running = false
+			unregisterShard(s)
+			s.mutex.Unlock()
+			return
+		}
+		s.mutex.Unlock()
 
-		mutex.Lock()
-		if len(timers) == 0 {
-			mutex.Unlock()
-			continue Loop
+		// Sleep until the next timer expires.
+		if ok {
+			sleepTimer.Reset(delta)
+			sleepTimerActive = true
 		}
+	}
+}
+
+// Fire all timers which expired at now.
+// It returns the duration until the next timer expires
+// and false if the heap is empty.
+func (s *shard) runTimersLocked(now time.Time) (time.Duration, bool) {
+	if s.wheel != nil {
+		return s.wheel.run(now)
+	}
+	if fairTags.Load() {
+		return s.runTimersFairLocked(now)
+	}
 
-		t := timers[0]
+	for len(s.timers) > 0 {
+		t := s.timers[0]
 		delta := t.when.Sub(now)
 
 		// Sleep if not expired.
 		if delta > 0 {
-			mutex.Unlock()
-			sleepTimer.Reset(delta)
-			sleepTimerActive = true
-			continue Loop
+			return delta, true
 		}
 
 		// Timer expired. Trigger the timer's function callback.
-		t.f(&now)
+		fireTimer(t, now)
+
+		// Periodic timers stay in the heap and are scheduled again.
+		if t.rearm(now) {
+			s.siftdownTimer(0)
+			continue
+		}
 
 		// Remove from heap.
-		last = len(timers) - 1
-		if last > 0 {
-			timers[0] = timers[last]
-			timers[0].i = 0
+		s.heapRemove(t)
+	}
+	return 0, false
+}
+
+// Fire all expired timers like runTimersLocked, but take turns between the
+// tags of the timers which expired together. Untagged timers take their
+// turns like timers with the same tag.
+func (s *shard) runTimersFairLocked(now time.Time) (time.Duration, bool) {
+	for len(s.timers) > 0 {
+		if delta := s.timers[0].when.Sub(now); delta > 0 {
+			return delta, true
 		}
-		timers[last] = nil
-		timers = timers[:last]
-		if last > 0 {
-			siftdownTimer(0)
+
+		// Collect the expired timers by tag in heap order.
+		var order []interface{}
+		lanes := make(map[interface{}][]*Timer)
+		for len(s.timers) > 0 && !s.timers[0].when.After(now) {
+			t := s.timers[0]
+			s.heapRemove(t)
+			if _, ok := lanes[t.tag]; !ok {
+				order = append(order, t.tag)
+			}
+			lanes[t.tag] = append(lanes[t.tag], t)
 		}
-		t.i = -1 // mark as removed
 
-		mutex.Unlock()
+		// Fire one timer of each tag in turn.
+		for len(order) > 0 {
+			next := order[:0]
+			for _, tag := range order {
+				lane := lanes[tag]
+				t := lane[0]
+				fireTimer(t, now)
+				if t.rearm(now) {
+					s.heapPush(t)
+				}
+				if len(lane) > 1 {
+					lanes[tag] = lane[1:]
+					next = append(next, tag)
+				}
+			}
+			order = next
+		}
+	}
+	return 0, false
+}
 
-		// Reschedule immediately.
-		goto Reschedule
+// Schedule the periodic timer t again after its fire at now. Periods which
+// already elapsed are skipped if the timer routine fell behind. A timer with
+// a next function is scheduled at the time returned by it instead.
+// It returns false if t is not scheduled again and must be removed.
+// Must be called with the lock held.
+func (t *Timer) rearm(now time.Time) bool {
+	switch {
+	case t.next != nil:
+		when := t.next(now)
+		if when.IsZero() {
+			return false
+		}
+		t.when = when
+		t.start = now
+	case t.period > 0:
+		t.when = t.when.Add(t.period * (1 + now.Sub(t.when)/t.period))
+		t.start = t.when.Add(-t.period)
+	default:
+		return false
 	}
+	t.s.seq++
+	t.seq = t.s.seq
+	return true
+}
+
+// Fire the expired timer t. Must be called with the lock held.
+// A panic is recovered and drops the timer: it is not rescheduled,
+// even if periodic, and the timer routine keeps running.
+func fireTimer(t *Timer, now time.Time) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.period = 0
+			if t.tag != nil {
+				untagTimer(t)
+			}
+			if h := runnerPanicHandler.Load(); h != nil {
+				(*h)(r)
+			}
+		}
+	}()
+
+	t.fired = true
+	totalFired.Add(1)
+	if t.minInterval > 0 {
+		t.lastFire = now
+	}
+	t.f(&now)
+	if t.notify != nil {
+		select {
+		case t.notify <- struct{}{}:
+		default:
+		}
+	}
+	if t.tag != nil && t.period <= 0 {
+		untagTimer(t)
+	}
+
+	if r := latencyRecorder.Load(); r != nil {
+		(*r)(t.when, now)
+	}
+}
+
+// Call the function f of an AfterFunc timer and pass
+// a panic to the callback panic handler if set.
+func runCallback(f func()) {
+	if h := callbackPanicHandler.Load(); h != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				(*h)(r, debug.Stack())
+			}
+		}()
+	}
+	f()
 }
 
 // Heap maintenance algorithms.
 // Based on golang source /runtime/time.go
 
-func siftupTimer(i int) {
+// Insert timer t into the heap.
+func (s *shard) heapPush(t *Timer) {
+	t.i = len(s.timers)
+	s.timers = append(s.timers, t)
+	s.siftupTimer(t.i)
+}
+
+// Remove timer t from the heap. t must be in the heap.
+func (s *shard) heapRemove(t *Timer) {
+	i := t.i
+	last := len(s.timers) - 1
+	if i != last {
+		s.timers[i] = s.timers[last]
+		s.timers[i].i = i
+	}
+	s.timers[last] = nil
+	s.timers = s.timers[:last]
+	if i != last {
+		s.siftupTimer(i)
+		s.siftdownTimer(i)
+	}
+	t.i = -1 // mark as removed
+}
+
+func (s *shard) siftupTimer(i int) {
+	timers := s.timers
 	tmp := timers[i]
-	when := tmp.when
 
 	var p int
 	for i > 0 {
 		p = (i - 1) / 4 // parent
-		if !when.Before(timers[p].when) {
+		if !tmp.before(timers[p]) {
 			break
 		}
 		timers[i] = timers[p]
@@ -177,9 +1508,9 @@ func siftupTimer(i int) {
 	}
 }
 
-func siftdownTimer(i int) {
+func (s *shard) siftdownTimer(i int) {
+	timers := s.timers
 	n := len(timers)
-	when := timers[i].when
 	tmp := timers[i]
 	for {
 		c := i*4 + 1 // left child
@@ -187,23 +1518,23 @@ func siftdownTimer(i int) {
 		if c >= n {
 			break
 		}
-		w := timers[c].when
-		if c+1 < n && timers[c+1].when.Before(w) {
-			w = timers[c+1].when
+		w := timers[c]
+		if c+1 < n && timers[c+1].before(w) {
+			w = timers[c+1]
 			c++
 		}
 		if c3 < n {
-			w3 := timers[c3].when
-			if c3+1 < n && timers[c3+1].when.Before(w3) {
-				w3 = timers[c3+1].when
+			w3 := timers[c3]
+			if c3+1 < n && timers[c3+1].before(w3) {
+				w3 = timers[c3+1]
 				c3++
 			}
-			if w3.Before(w) {
+			if w3.before(w) {
 				w = w3
 				c = c3
 			}
 		}
-		if !w.Before(when) {
+		if !w.before(tmp) {
 			break
 		}
 		timers[i] = timers[c]
@@ -213,3 +1544,16 @@ func siftdownTimer(i int) {
 		i = c
 	}
 }
+
+// Report whether timer t fires before timer u.
+// Timers with equal wake up times are ordered by their priority,
+// then in the order they were scheduled.
+func (t *Timer) before(u *Timer) bool {
+	if t.when.Equal(u.when) {
+		if t.prio != u.prio {
+			return t.prio < u.prio
+		}
+		return t.seq < u.seq
+	}
+	return t.when.Before(u.when)
+}
@@ -0,0 +1,560 @@
+package timer
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardDistribution(t *testing.T) {
+	n := len(*shards.Load())
+	counts := make(map[*shard]int)
+	for i := 0; i < 10*n; i++ {
+		counts[NewStoppedTimer().s]++
+	}
+	if len(counts) != n {
+		t.Errorf("shards: timers distributed to %v of %v shards", len(counts), n)
+	}
+}
+
+func BenchmarkShards(b *testing.B) {
+	for _, n := range []int{1, runtime.GOMAXPROCS(0)} {
+		for _, g := range []int{1, 4, 16, 64} {
+			b.Run(fmt.Sprintf("shards=%d/goroutines=%d", n, g), func(b *testing.B) {
+				benchmarkShards(b, n, g)
+			})
+		}
+	}
+}
+
+func benchmarkShards(b *testing.B, n, goroutines int) {
+	defer setShards(*shards.Load())
+	setShards(newShards(n))
+
+	var wg sync.WaitGroup
+	b.ResetTimer()
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < b.N/goroutines+1; i++ {
+				timer := NewTimer(time.Hour)
+				timer.Reset(time.Minute)
+				timer.Stop()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSetRunnerCount(t *testing.T) {
+	defer setShards(*shards.Load())
+
+	SetRunnerCount(3)
+	if n := len(*shards.Load()); n != 3 {
+		t.Fatalf("runner count: invalid shard count %v", n)
+	}
+	var timers []*Timer
+	for i := 0; i < 6; i++ {
+		timers = append(timers, NewTimer(0))
+	}
+	for _, timer := range timers {
+		<-timer.C
+	}
+
+	SetRunnerCount(0)
+	if n := len(*shards.Load()); n != 1 {
+		t.Fatalf("runner count: invalid shard count %v", n)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	defer setShards(*shards.Load())
+	setShards(newShards(2))
+	ss := *shards.Load()
+
+	running := func() (n int) {
+		for _, s := range ss {
+			s.mutex.Lock()
+			if s.running {
+				n++
+			}
+			s.mutex.Unlock()
+		}
+		return
+	}
+
+	timer := NewTimer(time.Hour)
+	Shutdown()
+	if n := running(); n != 1 {
+		t.Fatalf("shutdown: invalid running routines %v", n)
+	}
+
+	timer.Stop()
+	Shutdown()
+	if n := running(); n != 0 {
+		t.Fatalf("shutdown: invalid running routines %v", n)
+	}
+
+	// Routines restart on demand.
+	timer = NewTimer(10 * time.Millisecond)
+	select {
+	case <-timer.C:
+	case <-time.After(time.Second):
+		t.Fatalf("shutdown: timer did not fire after restart")
+	}
+	if n := running(); n != 1 {
+		t.Fatalf("shutdown: invalid running routines after restart %v", n)
+	}
+}
+
+func TestIsIdle(t *testing.T) {
+	timer := NewTimer(time.Hour)
+	if IsIdle() {
+		t.Errorf("is idle: true with an active timer")
+	}
+	timer.Stop()
+}
+
+func BenchmarkRunnerCount(b *testing.B) {
+	for _, n := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("runners=%d", n), func(b *testing.B) {
+			defer setShards(*shards.Load())
+			SetRunnerCount(n)
+
+			ds := make([]time.Duration, 10000)
+			for i := range ds {
+				ds[i] = time.Millisecond
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, timer := range NewTimers(ds) {
+					<-timer.C
+				}
+			}
+		})
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	SetCoalesceWindow(100 * time.Millisecond)
+	defer SetCoalesceWindow(0)
+
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		when := now.Add(time.Duration(i) * time.Millisecond)
+		c := coalesce(when)
+		if c.Before(when) || c.Sub(when) >= 100*time.Millisecond {
+			t.Fatalf("coalesce: invalid wake up time: %v for %v", c, when)
+		}
+		if c.UnixNano()%int64(100*time.Millisecond) != 0 {
+			t.Fatalf("coalesce: wake up time is not aligned: %v", c)
+		}
+		if !hasMonotonic(c) {
+			t.Fatalf("coalesce: wake up time has no monotonic clock reading")
+		}
+	}
+
+	start := time.Now()
+	timer := NewTimer(time.Millisecond)
+	<-timer.C
+	if time.Since(start) < time.Millisecond {
+		t.Errorf("coalesce: timer fired early")
+	}
+}
+
+func BenchmarkCoalesce(b *testing.B) {
+	for _, w := range []time.Duration{0, 10 * time.Millisecond} {
+		b.Run(fmt.Sprintf("window=%v", w), func(b *testing.B) {
+			SetCoalesceWindow(w)
+			defer SetCoalesceWindow(0)
+
+			ds := make([]time.Duration, 50000)
+			for i := range ds {
+				ds[i] = time.Duration(i) * 100 * time.Millisecond / time.Duration(len(ds))
+			}
+
+			b.ResetTimer()
+			start := wakeups.Load()
+			for i := 0; i < b.N; i++ {
+				for _, timer := range NewTimers(ds) {
+					<-timer.C
+				}
+			}
+			b.ReportMetric(float64(wakeups.Load()-start)/float64(b.N), "wakeups/op")
+		})
+	}
+}
+
+func TestPriorityOrder(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+
+	const n = 1000
+	var order []int
+	for _, p := range rand.Perm(n) {
+		p := p
+		timer := newStoppedTimer(options{
+			clock:  clk,
+			onFire: func(time.Time) { order = append(order, p) },
+		})
+		timer.prio = p
+		addTimer(timer, time.Second)
+	}
+
+	clk.Advance(time.Second)
+	if len(order) != n {
+		t.Fatalf("priority: invalid fire count %v", len(order))
+	}
+	for i, p := range order {
+		if p != i {
+			t.Fatalf("priority: timer with priority %v fired at position %v", p, i)
+		}
+	}
+}
+
+func TestFIFOOrder(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+
+	const n = 1000
+	var order []int
+	timers := make([]*Timer, n)
+	for i := range timers {
+		i := i
+		timers[i] = NewTimerWithOptions(time.Second, WithClock(clk),
+			WithOnFire(func(time.Time) { order = append(order, i) }))
+	}
+
+	// A reset moves the timer behind the timers already scheduled
+	// for the same time.
+	var want []int
+	for i := range timers {
+		if i%10 != 0 {
+			want = append(want, i)
+		}
+	}
+	for i := 0; i < n; i += 10 {
+		timers[i].Reset(time.Second)
+		want = append(want, i)
+	}
+
+	clk.Advance(time.Second)
+	if len(order) != n {
+		t.Fatalf("fifo: invalid fire count %v", len(order))
+	}
+	for i, j := range order {
+		if j != want[i] {
+			t.Fatalf("fifo: timer %v fired at position %v, should be timer %v", j, i, want[i])
+		}
+	}
+}
+
+func TestNewTimerPrio(t *testing.T) {
+	a, b := NewTimerPrio(time.Hour, 1), NewTimerPrio(time.Hour, 0)
+	defer StopAll([]*Timer{a, b})
+
+	if a.s != b.s {
+		t.Errorf("priority: timers do not share a shard")
+	}
+	if a.prio != 1 || b.prio != 0 {
+		t.Errorf("priority: invalid priorities %v %v", a.prio, b.prio)
+	}
+}
+
+func TestShiftAll(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+
+	const n = 10000
+	var fires []*Timer
+	timers := make([]*Timer, n)
+	deadlines := make([]time.Time, n)
+	for i := range timers {
+		var timer *Timer
+		timer = newStoppedTimer(options{
+			clock:  clk,
+			onFire: func(time.Time) { fires = append(fires, timer) },
+		})
+		addTimer(timer, time.Duration(1+rand.Intn(n))*time.Millisecond)
+		timers[i] = timer
+		deadlines[i] = timer.Deadline()
+	}
+
+	ShiftAll(time.Hour)
+	defer ShiftAll(-time.Hour) // Restore the timers of other tests.
+
+	for i, timer := range timers {
+		if d := timer.Deadline().Sub(deadlines[i]); d != time.Hour {
+			t.Fatalf("shift all: timer shifted by %v", d)
+		}
+	}
+
+	clk.Advance(time.Hour)
+	if len(fires) != 0 {
+		t.Fatalf("shift all: %v timers fired early", len(fires))
+	}
+	clk.Advance(n * time.Millisecond)
+	if len(fires) != n {
+		t.Fatalf("shift all: invalid fire count %v", len(fires))
+	}
+	for i := 1; i < n; i++ {
+		if fires[i].Deadline().Before(fires[i-1].Deadline()) {
+			t.Fatalf("shift all: order not preserved at %v", i)
+		}
+	}
+}
+
+func TestShiftAllNegative(t *testing.T) {
+	clk := NewFakeClock(time.Unix(0, 0))
+	timer := NewTimerWithClock(clk, time.Hour)
+
+	ShiftAll(-time.Minute)
+	defer ShiftAll(time.Minute)
+	if !timer.Deadline().Equal(time.Unix(59*60, 0)) {
+		t.Errorf("shift all: invalid deadline %v", timer.Deadline())
+	}
+
+	ShiftAll(-time.Hour)
+	defer ShiftAll(time.Hour)
+	if len(timer.C) != 1 {
+		t.Errorf("shift all: expired timer did not fire")
+	}
+}
+
+// Verify the heap order and the indexes of the shard's timers.
+func checkHeap(t *testing.T, s *shard) {
+	t.Helper()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.checkHeapLocked(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetInPlace(t *testing.T) {
+	defer setShards(*shards.Load())
+	setShards(newShards(1))
+	s := (*shards.Load())[0]
+
+	timers := make([]*Timer, 1000)
+	for i := range timers {
+		timers[i] = NewTimer(time.Duration(1+rand.Intn(1000)) * time.Hour)
+	}
+	defer StopAll(timers)
+	checkHeap(t, s)
+
+	for i := 0; i < 10000; i++ {
+		timer := timers[rand.Intn(len(timers))]
+		if !timer.Reset(time.Duration(1+rand.Intn(1000)) * time.Hour) {
+			t.Fatalf("reset in place: was active is false")
+		}
+	}
+	checkHeap(t, s)
+
+	// The root is moved down and the next timer becomes the root.
+	root := s.timers[0]
+	root.Reset(2000 * time.Hour)
+	checkHeap(t, s)
+	if s.timers[0] == root {
+		t.Errorf("reset in place: root was not moved down")
+	}
+	if n := len(s.timers); n != len(timers) {
+		t.Errorf("reset in place: invalid heap size %v", n)
+	}
+}
+
+func BenchmarkResetRoot(b *testing.B) {
+	defer setShards(*shards.Load())
+	setShards(newShards(1))
+
+	timers := make([]*Timer, 10000)
+	for i := range timers {
+		timers[i] = NewTimer(time.Duration(2+i) * time.Hour)
+	}
+	defer StopAll(timers)
+
+	timer := NewTimer(time.Hour)
+	defer timer.Stop()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		timer.Reset(time.Hour + time.Duration(i))
+	}
+}
+
+func TestConcurrentReset(t *testing.T) {
+	defer setShards(*shards.Load())
+	setShards(newShards(1))
+	s := (*shards.Load())[0]
+
+	timer := NewTimer(time.Hour)
+	ds := []time.Duration{time.Hour, 2 * time.Hour, 3 * time.Hour, 4 * time.Hour}
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 64; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				if i%100 == 99 {
+					timer.Stop()
+				}
+				timer.Reset(ds[(g+i)%len(ds)])
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	s.mutex.Lock()
+	var n int
+	for _, ht := range s.timers {
+		if ht == timer {
+			n++
+		}
+	}
+	s.mutex.Unlock()
+	if n != 1 {
+		t.Fatalf("concurrent reset: timer scheduled %v times", n)
+	}
+	checkHeap(t, s)
+
+	var ok bool
+	for _, d := range ds {
+		if dl := timer.Deadline(); !dl.Before(start.Add(d)) && dl.Before(time.Now().Add(d)) {
+			ok = true
+		}
+	}
+	if !ok {
+		t.Errorf("concurrent reset: deadline %v is none of the requested", timer.Deadline())
+	}
+	if len(timer.C) != 0 {
+		t.Errorf("concurrent reset: channel not drained")
+	}
+	timer.Stop()
+}
+
+// Timers with durations below the resolution of the timer routine expire
+// before it wakes up. They are fired together without a wake up each.
+func TestTinyDurationWakeups(t *testing.T) {
+	const n = 10000
+	start := wakeups.Load()
+
+	timers := make([]*Timer, n)
+	for i := range timers {
+		timers[i] = NewTimer(time.Nanosecond)
+	}
+	for _, timer := range timers {
+		<-timer.C
+	}
+	if w := wakeups.Load() - start; w > n/2 {
+		t.Errorf("tiny durations: %v wake ups for %v timers", w, n)
+	}
+}
+
+func TestTimeScale(t *testing.T) {
+	defer SetTimeScale(1)
+
+	SetTimeScale(2)
+	start := time.Now()
+	timer := NewTimer(2 * time.Second)
+	<-timer.C
+	if d := time.Since(start); d < 900*time.Millisecond || d > 1500*time.Millisecond {
+		t.Errorf("time scale: 2s timer fired after %v at factor 2", d)
+	}
+
+	// Scheduled timers are rescaled.
+	SetTimeScale(1)
+	start = time.Now()
+	timer.Reset(4 * time.Second)
+	SetTimeScale(4)
+	<-timer.C
+	if d := time.Since(start); d < 900*time.Millisecond || d > 1500*time.Millisecond {
+		t.Errorf("time scale: rescaled 4s timer fired after %v at factor 4", d)
+	}
+
+	// Tiny factors saturate instead of overflowing.
+	SetTimeScale(1e-300)
+	timer.Reset(time.Second)
+	if !timer.Active() || timer.Remaining() < 100*365*24*time.Hour {
+		t.Errorf("time scale: invalid remaining duration %v at tiny factor", timer.Remaining())
+	}
+	timer.Stop()
+
+	for _, f := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("time scale: factor %v did not panic", f)
+				}
+			}()
+			SetTimeScale(f)
+		}()
+	}
+}
+
+func TestUrgentTimer(t *testing.T) {
+	// Flood the ordinary timer routines with a backlog of slow fires.
+	const n = 20000
+	slow := func(time.Time) {
+		for start := time.Now(); time.Since(start) < 50*time.Microsecond; {
+		}
+	}
+	timers := make([]*Timer, n)
+	for i := range timers {
+		timers[i] = NewTimerWithOptions(10*time.Millisecond, WithOnFire(slow))
+	}
+	defer StopAll(timers)
+
+	start := time.Now()
+	urgent := NewUrgentTimer(20 * time.Millisecond)
+	if urgent.s == timers[0].s {
+		t.Fatalf("urgent timer: shares the shard of ordinary timers")
+	}
+	v := <-urgent.C
+	if d := v.Sub(start); d > 200*time.Millisecond {
+		t.Errorf("urgent timer: fired after %v with a backlog of ordinary fires", d)
+	}
+}
+
+func TestHeapInvariantStress(t *testing.T) {
+	const n = 1000
+	timers := make([]*Timer, n)
+	for i := range timers {
+		timers[i] = NewTimer(time.Duration(rand.Intn(n)) * time.Millisecond)
+	}
+	defer StopAll(timers)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(r *rand.Rand) {
+			defer wg.Done()
+			for i := 0; i < 10000; i++ {
+				timer := timers[r.Intn(n)]
+				d := time.Duration(r.Intn(n)) * time.Microsecond
+				switch r.Intn(6) {
+				case 0:
+					timer.Stop()
+				case 1:
+					timer.ResetPeriodic(d + time.Millisecond)
+				case 2:
+					timer.Pause()
+				case 3:
+					timer.Resume()
+				default:
+					timer.Reset(d)
+				}
+			}
+		}(rand.New(rand.NewSource(int64(g))))
+	}
+	wg.Wait()
+
+	if err := checkHeapInvariant(); err != nil {
+		t.Fatal(err)
+	}
+}
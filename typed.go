@@ -0,0 +1,66 @@
+package timer
+
+import (
+	"time"
+)
+
+// A TypedTimer is a Timer which sends a typed value instead of the current
+// time on its channel C. Stop and Reset behave like Timer's methods and
+// drain the typed channel.
+// A TypedTimer must be created with NewTypedTimer.
+type TypedTimer[T any] struct {
+	C <-chan T
+
+	t *Timer
+}
+
+// NewTypedTimer creates a new TypedTimer that will send value on its
+// channel after at least duration d.
+func NewTypedTimer[T any](d time.Duration, value T) *TypedTimer[T] {
+	c := make(chan T, 1)
+	t := &Timer{
+		s: pickShard(),
+		f: func(*time.Time) {
+			// Don't block.
+			select {
+			case c <- value:
+			default:
+			}
+		},
+		reset: func() bool {
+			// Empty the channel if filled.
+			select {
+			case <-c:
+				return true
+			default:
+				return false
+			}
+		},
+	}
+	addTimer(t, d)
+	return &TypedTimer[T]{
+		C: c,
+		t: t,
+	}
+}
+
+// Stop prevents the TypedTimer from firing.
+// It returns true if the call stops the timer,
+// false if the timer has already expired or been stopped.
+func (t *TypedTimer[T]) Stop() bool {
+	if t.t == nil {
+		panic("timer: Stop called on uninitialized TypedTimer")
+	}
+	return delTimer(t.t)
+}
+
+// Reset changes the timer to expire after duration d.
+// It returns true if the timer had been active,
+// false if the timer had expired or been stopped.
+// The channel t.C is cleared.
+func (t *TypedTimer[T]) Reset(d time.Duration) bool {
+	if t.t == nil {
+		panic("timer: Reset called on uninitialized TypedTimer")
+	}
+	return resetTimer(t.t, d, 0)
+}
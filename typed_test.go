@@ -0,0 +1,32 @@
+package timer
+
+import (
+	"testing"
+	"time"
+)
+
+type event struct {
+	id int
+}
+
+func TestTypedTimer(t *testing.T) {
+	timer := NewTypedTimer(100*time.Millisecond, event{id: 1})
+	if v := <-timer.C; v.id != 1 {
+		t.Errorf("typed timer: invalid value %v", v)
+	}
+
+	// Reset drains the typed channel.
+	time.Sleep(50 * time.Millisecond)
+	timer.Reset(0)
+	time.Sleep(50 * time.Millisecond)
+	if wasActive := timer.Reset(time.Second); wasActive {
+		t.Errorf("typed timer: was active is true")
+	}
+	if len(timer.C) != 0 {
+		t.Errorf("typed timer: channel should be empty")
+	}
+
+	if !timer.Stop() {
+		t.Errorf("typed timer: was active is false")
+	}
+}
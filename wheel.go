@@ -0,0 +1,162 @@
+package timer
+
+import (
+	"time"
+)
+
+// A wheel is a hashed timing wheel which replaces the heap of a shard.
+// Time is divided into ticks and each timer is stored in the bucket of the
+// tick it expires in, modulo the number of buckets. Buckets hold timers of
+// multiple rounds of the wheel, which are skipped until they expired.
+// Adding and removing timers is O(1). Timers are fired at tick boundaries.
+type wheel struct {
+	tick    time.Duration
+	buckets [][]*Timer
+	start   time.Time // origin of the tick numbering.
+	cur     int64     // next tick to process.
+	n       int       // number of timers.
+}
+
+// UseTimingWheel replaces the timer heap by a hashed timing wheel with
+// wheelSize buckets of duration tickDuration for timers created afterwards.
+// Timers created before keep their heap.
+//
+// Adding and stopping timers is O(1) instead of O(log n), which pays off
+// for hundreds of thousands of coarse-grained timers, like idle timeouts.
+// The resolution is bounded by tickDuration: timers fire up to one tick
+// late, but never early. The timer routine wakes up every tick while timers
+// are scheduled. Choose wheelSize such that tickDuration*wheelSize covers
+// the typical timeouts, as timers expiring in later rounds are skipped on
+// each visit of their bucket.
+//
+// UseTimingWheel should be called during program initialization.
+func UseTimingWheel(tickDuration time.Duration, wheelSize int) {
+	if tickDuration <= 0 {
+		panic("timer: non-positive tick duration for UseTimingWheel")
+	}
+	if wheelSize <= 0 {
+		panic("timer: non-positive wheel size for UseTimingWheel")
+	}
+
+	ss := make([]*shard, len(*shards.Load()))
+	for i := range ss {
+		ss[i] = newShard(newWheel(tickDuration, wheelSize))
+	}
+	setShards(ss)
+}
+
+func newWheel(tick time.Duration, size int) *wheel {
+	return &wheel{
+		tick:    tick,
+		buckets: make([][]*Timer, size),
+		start:   SystemClock.Now(),
+	}
+}
+
+// Return the first tick at which when has expired.
+func (w *wheel) tickOf(when time.Time) int64 {
+	d := when.Sub(w.start)
+	tk := int64(d / w.tick)
+	if d%w.tick > 0 {
+		tk++
+	}
+	return tk
+}
+
+func (w *wheel) add(t *Timer) {
+	tk := w.tickOf(t.when)
+	if tk < w.cur {
+		tk = w.cur
+	}
+	t.b = int(tk % int64(len(w.buckets)))
+	t.i = len(w.buckets[t.b])
+	w.buckets[t.b] = append(w.buckets[t.b], t)
+	w.n++
+}
+
+func (w *wheel) active(t *Timer) bool {
+	b, i := t.b, t.i
+	return b >= 0 && b < len(w.buckets) && i >= 0 && i < len(w.buckets[b]) && w.buckets[b][i] == t
+}
+
+func (w *wheel) del(t *Timer) bool {
+	if !w.active(t) {
+		return false
+	}
+	w.remove(t)
+	return true
+}
+
+func (w *wheel) remove(t *Timer) {
+	bucket := w.buckets[t.b]
+	last := len(bucket) - 1
+	if t.i != last {
+		bucket[t.i] = bucket[last]
+		bucket[t.i].i = t.i
+	}
+	bucket[last] = nil
+	w.buckets[t.b] = bucket[:last]
+	w.n--
+	t.i = -1 // mark as removed
+}
+
+// Add delta to the wake up time of all timers and rehash them.
+func (w *wheel) shift(delta time.Duration) {
+	w.rehash(func(t *Timer) {
+		t.when = t.when.Add(delta)
+	})
+}
+
+// Call f for each timer, which might change its wake up time,
+// and rehash all timers.
+func (w *wheel) rehash(f func(t *Timer)) {
+	var ts []*Timer
+	for b, bucket := range w.buckets {
+		ts = append(ts, bucket...)
+		clear(bucket)
+		w.buckets[b] = bucket[:0]
+	}
+	w.n = 0
+	for _, t := range ts {
+		f(t)
+		w.add(t)
+	}
+}
+
+// Fire all timers which expired at now.
+// It returns the duration until the next tick
+// and false if the wheel is empty.
+func (w *wheel) run(now time.Time) (time.Duration, bool) {
+	end := int64(now.Sub(w.start) / w.tick)
+
+	// Visiting each bucket once is enough if we fell behind a whole round.
+	if end-w.cur >= int64(len(w.buckets)) {
+		w.cur = end - int64(len(w.buckets)) + 1
+	}
+
+	for ; w.cur <= end && w.n > 0; w.cur++ {
+		b := int(w.cur % int64(len(w.buckets)))
+
+		// Iterate backwards, because removing swaps the last timer
+		// to the current position.
+		for i := len(w.buckets[b]) - 1; i >= 0; i-- {
+			t := w.buckets[b][i]
+			if t.when.After(now) {
+				continue // A later round.
+			}
+
+			w.remove(t)
+			fireTimer(t, now)
+
+			// Periodic timers are added again.
+			if t.rearm(now) {
+				w.add(t)
+			}
+		}
+	}
+	if w.n == 0 {
+		return 0, false
+	}
+	w.cur = end + 1
+	return w.start.Add(time.Duration(w.cur) * w.tick).Sub(now), true
+}
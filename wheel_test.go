@@ -0,0 +1,168 @@
+package timer
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Run f with timers created on timing wheel shards.
+func withTimingWheel(tick time.Duration, size int, f func()) {
+	defer setShards(*shards.Load())
+	UseTimingWheel(tick, size)
+	f()
+}
+
+func TestTimingWheel(t *testing.T) {
+	const tick = 10 * time.Millisecond
+	withTimingWheel(tick, 16, func() {
+		var timers []*Timer
+		var starts []time.Time
+		for i := 0; i < 100; i++ {
+			starts = append(starts, time.Now())
+			// Durations exceed a whole round of the wheel.
+			timers = append(timers, NewTimer(time.Duration(i)*3*time.Millisecond))
+		}
+		if n := ActiveTimers(); n < 100 {
+			t.Errorf("timing wheel: invalid active timers: %v", n)
+		}
+
+		for i, timer := range timers {
+			d := time.Duration(i) * 3 * time.Millisecond
+			v := <-timer.C
+			if elapsed := v.Sub(starts[i]); elapsed < d || elapsed > d+tick+50*time.Millisecond {
+				t.Errorf("timing wheel: timer %v fired after %v", d, elapsed)
+			}
+		}
+	})
+}
+
+func TestTimingWheelStopReset(t *testing.T) {
+	withTimingWheel(10*time.Millisecond, 8, func() {
+		timer := NewTimer(50 * time.Millisecond)
+		if !timer.Active() {
+			t.Errorf("timing wheel: timer is not active")
+		}
+		if !timer.Stop() {
+			t.Errorf("timing wheel: was active is false")
+		}
+		if timer.Stop() || timer.Active() {
+			t.Errorf("timing wheel: stopped timer is active")
+		}
+
+		start := time.Now()
+		if timer.Reset(100 * time.Millisecond) {
+			t.Errorf("timing wheel: was active is true")
+		}
+		<-timer.C
+		if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > 200*time.Millisecond {
+			t.Errorf("timing wheel: reset timer fired after %v", elapsed)
+		}
+
+		ticker := NewTicker(20 * time.Millisecond)
+		for i := 0; i < 3; i++ {
+			<-ticker.C
+		}
+		ticker.Stop()
+	})
+}
+
+func BenchmarkTimingWheel(b *testing.B) {
+	for _, n := range []int{100000, 1000000} {
+		b.Run(fmt.Sprintf("heap/timers=%d", n), func(b *testing.B) {
+			benchmarkInsertStop(b, n)
+		})
+		b.Run(fmt.Sprintf("wheel/timers=%d", n), func(b *testing.B) {
+			withTimingWheel(time.Second, 512, func() {
+				benchmarkInsertStop(b, n)
+			})
+		})
+	}
+}
+
+func benchmarkInsertStop(b *testing.B, n int) {
+	b.ReportAllocs()
+	timers := make([]*Timer, n)
+	for i := range timers {
+		timers[i] = NewStoppedTimer()
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j, timer := range timers {
+			timer.Reset(time.Duration(j%300) * time.Second)
+		}
+		for _, timer := range timers {
+			timer.Stop()
+		}
+	}
+}
+
+func TestTimingWheelActiveTimers(t *testing.T) {
+	heapTimer := NewTimer(time.Hour)
+	defer heapTimer.Stop()
+	n := ActiveTimers()
+
+	withTimingWheel(time.Second, 8, func() {
+		// Timers of the replaced heap shards are still counted.
+		timer := NewTimer(time.Hour)
+		if a := ActiveTimers(); a != n+1 {
+			t.Errorf("timing wheel: invalid active timers: %v", a)
+		}
+		timer.Stop()
+	})
+
+	// The retired wheel shards are empty and their timer routines exit.
+	// The shard of urgent timers is not replaced.
+	urgent := urgentShard()
+	want := func() int {
+		n := len(*shards.Load())
+		registryMutex.Lock()
+		for s := range registry {
+			if s.rescheduleC == nil || s == urgent {
+				n++
+			}
+		}
+		registryMutex.Unlock()
+		return n
+	}
+	for i := 0; i < 100; i++ {
+		if len(allShards()) == want() {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if l := len(allShards()); l != want() {
+		t.Errorf("timing wheel: retired shards were not released: %v", l)
+	}
+}
+
+func TestRetiredShardRestart(t *testing.T) {
+	var timer *Timer
+	withTimingWheel(10*time.Millisecond, 8, func() {
+		timer = NewStoppedTimer()
+	})
+
+	// The timer routine of the retired and empty shard is restarted.
+	timer.Reset(50 * time.Millisecond)
+	select {
+	case <-timer.C:
+	case <-time.After(time.Second):
+		t.Errorf("retired shard: timer did not fire")
+	}
+}
+
+func TestTimingWheelRunnerCount(t *testing.T) {
+	withTimingWheel(time.Millisecond, 64, func() {
+		SetRunnerCount(2)
+		ss := *shards.Load()
+		if len(ss) != 2 {
+			t.Fatalf("timing wheel: invalid shard count %v", len(ss))
+		}
+		for _, s := range ss {
+			if s.wheel == nil || s.wheel.tick != time.Millisecond || len(s.wheel.buckets) != 64 {
+				t.Fatalf("timing wheel: wheel not kept by SetRunnerCount")
+			}
+		}
+	})
+}